@@ -0,0 +1,117 @@
+package main
+
+import (
+  "fmt"
+  "math"
+  "os"
+  "sort"
+)
+
+// Budget mode (-budget): picks the games under target to buy with a given amount.
+
+// Games without priority are worth 1 so that they are still considered.
+func basketValue(game Game) int {
+  if game.criteria.priority < 1 {
+    return 1
+  }
+  return game.criteria.priority
+}
+
+func priceInCents(price float32) int {
+  return int(math.Round(float64(price) * 100))
+}
+
+// Returns the set of |games| that maximizes the total priority within |budget|.
+// Among the sets with the same priority, the cheapest one is chosen.
+// This is the 0/1 knapsack problem, solved by dynamic programming over the total priority
+// rather than the cents: the table stays small whatever the budget.
+func selectBasket(games []Game, budget float32) []Game {
+  capacity := priceInCents(budget)
+  if capacity < 0 {
+    return []Game{}
+  }
+  affordable := []Game{}
+  totalValue := 0
+  for _, game := range games {
+    if priceInCents(game.minPrice) <= capacity {
+      affordable = append(affordable, game)
+      totalValue += basketValue(game)
+    }
+  }
+
+  // cheapest[v] is the lowest cost for a total priority of exactly v, -1 if unreachable.
+  // taken[i][v] records whether affordable[i] is in the set for cheapest[v] after considering affordable[:i+1].
+  cheapest := make([]int, totalValue + 1)
+  for v := 1; v <= totalValue; v++ {
+    cheapest[v] = -1
+  }
+  taken := make([][]bool, len(affordable))
+  for i, game := range affordable {
+    taken[i] = make([]bool, totalValue + 1)
+    cost := priceInCents(game.minPrice)
+    value := basketValue(game)
+    for v := totalValue; v >= value; v-- {
+      if cheapest[v - value] < 0 {
+        continue
+      }
+      if cost := cheapest[v - value] + cost; cheapest[v] < 0 || cost < cheapest[v] {
+        cheapest[v] = cost
+        taken[i][v] = true
+      }
+    }
+  }
+
+  // The highest priority within the budget.
+  v := totalValue
+  for cheapest[v] < 0 || cheapest[v] > capacity {
+    v--
+  }
+
+  basket := []Game{}
+  for i := len(affordable) - 1; i >= 0; i-- {
+    if taken[i][v] {
+      basket = append(basket, affordable[i])
+      v -= basketValue(affordable[i])
+    }
+  }
+  sort.Sort(ByPriceThenName(basket))
+  return basket
+}
+
+func printBasket(basket []Game, budget float32) {
+  fmt.Fprintf(os.Stdout, "==================================================\n")
+  fmt.Fprintf(os.Stdout, "================= Budget basket ==================\n")
+  fmt.Fprintf(os.Stdout, "==================================================\n")
+  fmt.Fprintf(os.Stdout, "Budget: $%.2f\n\n", budget)
+  if len(basket) == 0 {
+    fmt.Fprintf(os.Stdout, "No game under target fits the budget\n")
+    fmt.Fprintf(os.Stdout, "\n\n")
+    return
+  }
+
+  subtotals := make(map[string]float32)
+  stores := []string{}
+  total := float32(0)
+  priority := 0
+  for _, game := range basket {
+    if game.criteria.priority > 0 {
+      fmt.Fprintf(os.Stdout, "%s: $%.2f (priority %d) - %s\n", game.name, game.minPrice, game.criteria.priority, game.url())
+    } else {
+      fmt.Fprintf(os.Stdout, "%s: $%.2f - %s\n", game.name, game.minPrice, game.url())
+    }
+    if _, found := subtotals[game.backend]; !found {
+      stores = append(stores, game.backend)
+    }
+    subtotals[game.backend] += game.minPrice
+    total += game.minPrice
+    priority += basketValue(game)
+  }
+
+  fmt.Fprintf(os.Stdout, "\n")
+  sort.Strings(stores)
+  for _, store := range stores {
+    fmt.Fprintf(os.Stdout, "%s: $%.2f\n", store, subtotals[store])
+  }
+  fmt.Fprintf(os.Stdout, "Total: $%.2f (total priority %d, $%.2f left)\n", total, priority, budget - total)
+  fmt.Fprintf(os.Stdout, "\n\n")
+}
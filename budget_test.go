@@ -0,0 +1,50 @@
+package main
+
+import (
+  "reflect"
+  "testing"
+)
+
+func TestSelectBasket(t *testing.T) {
+  game := func(name string, price float32, priority int) Game {
+    game := pricedGame(price, -1)
+    game.name = name
+    game.criteria.priority = priority
+    return game
+  }
+  games := []Game{
+    game("Expensive", 40, 5),
+    game("Cheap", 10, 2),
+    game("Cheaper", 9.99, 2),
+    game("Mid", 20, 3),
+    game("Unranked", 5, 0),
+    game("Free", 0, 0),
+  }
+
+  tt := []struct {
+    name string
+    budget float32
+    expected []string
+  } {
+    {"Everything", 100, []string{"Free", "Unranked", "Cheaper", "Cheap", "Mid", "Expensive"}},
+    // Expensive + Cheaper + Free (8) fits but the cheaper games add up to more (9).
+    {"Highest total priority", 50, []string{"Free", "Unranked", "Cheaper", "Cheap", "Mid"}},
+    // Cheaper is preferred to Cheap for the same priority.
+    {"Cheapest on ties", 15, []string{"Free", "Unranked", "Cheaper"}},
+    {"Exact budget", 9.99, []string{"Free", "Cheaper"}},
+    {"Nothing fits", 0, []string{"Free"}},
+    {"Large budget", 1000000, []string{"Free", "Unranked", "Cheaper", "Cheap", "Mid", "Expensive"}},
+  }
+
+  for _, tc := range(tt) {
+    t.Run(tc.name, func(t *testing.T) {
+      names := []string{}
+      for _, game := range selectBasket(games, tc.budget) {
+        names = append(names, game.name)
+      }
+      if !reflect.DeepEqual(names, tc.expected) {
+        t.Errorf("Expected %+v but got %+v", tc.expected, names)
+      }
+    })
+  }
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+  "encoding/json"
+  "errors"
+  "flag"
+  "fmt"
+  "io/fs"
+  "os"
+  "strconv"
+  "strings"
+)
+
+// Subcommands, called with the arguments following the subcommand name.
+var subcommands = map[string]func(args []string) error {
+  "import-steam": runImportSteam,
+  "import": runImport,
+  "add": runAdd,
+  "remove": runRemove,
+  "set-target": runSetTarget,
+  "list": runList,
+  "dedupe": runDedupe,
+}
+
+// Returns the Steam ids of |entry|, either pinned or from a Steam reference name.
+func (entry watchlistEntry) steamIds() pinnedIds {
+  ids, _ := parseSteamReference(entry.Name)
+  if entry.Pinned != nil && entry.Pinned.Steam != 0 {
+    ids = pinnedIds{steamId: entry.Pinned.Steam}
+  }
+  if entry.Pinned != nil && entry.Pinned.Bundle != 0 {
+    ids = pinnedIds{steamBundleId: entry.Pinned.Bundle}
+  }
+  return ids
+}
+
+// Whether |entry| is designated by |name|, either a game name or a Steam reference.
+func (entry watchlistEntry) matches(name string) bool {
+  ids, isReference := parseSteamReference(name)
+  if isReference {
+    return entry.steamIds() == ids
+  }
+  return normalizeName(entry.Name) == normalizeName(name)
+}
+
+// Targets are written as numbers when possible for readability.
+func rawTarget(expr string) json.RawMessage {
+  _, err := strconv.ParseFloat(expr, /*bitSize=*/32)
+  if err == nil {
+    return json.RawMessage(expr)
+  }
+  raw, _ := json.Marshal(expr)
+  return raw
+}
+
+// Parses the subcommand flags, checking that -file was provided.
+func parseSubcommandFlags(flags *flag.FlagSet, watchlistFile *string, args []string) error {
+  flags.Parse(args)
+  if *watchlistFile == "" {
+    flags.Usage()
+    return errors.New("-file is mandatory")
+  }
+  return nil
+}
+
+// watcher add -file <watchlist> [-target <target>] [-owner <owner>] <game>...
+// Games are checked against Steam and written with their Steam name and id.
+func runAdd(args []string) error {
+  flags := flag.NewFlagSet("add", flag.ExitOnError)
+  watchlistFile := flags.String("file", "", "Watchlist to add to (created if missing)")
+  target := flags.String("target", "", "Target for the added games (default target if empty)")
+  owner := flags.String("owner", "", "Owner of the added games in a shared watchlist")
+  err := parseSubcommandFlags(flags, watchlistFile, args)
+  if err != nil {
+    return err
+  }
+  if *target != "" {
+    _, err = parseTarget(*target)
+    if err != nil {
+      return err
+    }
+  }
+
+  existing := []gameCriteria{}
+  _, err = os.Stat(*watchlistFile)
+  if err == nil {
+    existing, err = parseWatchlistFile(*watchlistFile)
+    if err != nil {
+      return err
+    }
+  } else if !errors.Is(err, fs.ErrNotExist) {
+    return err
+  }
+
+  entries := []watchlistEntry{}
+  missing := 0
+  for _, name := range flags.Args() {
+    entry := watchlistEntry{Owner: *owner}
+    if *target != "" {
+      entry.Target = rawTarget(*target)
+    }
+
+    if query, isQuery := parseSteamQuery(name); isQuery {
+      err, games := SearchQueryOnSteam(query)
+      if err != nil {
+        return err
+      }
+      if len(games) == 0 {
+        fmt.Fprintf(os.Stderr, "No Steam game for \"%s\"\n", name)
+        missing += 1
+        continue
+      }
+      entry.Name = name
+    } else {
+      pinned, _ := parseSteamReference(name)
+      err, game := SearchGameOnSteam(name, pinned)
+      if err != nil {
+        return err
+      }
+      if game == nil {
+        fmt.Fprintf(os.Stderr, "No Steam game for \"%s\" (did you mistype the name?)\n", name)
+        missing += 1
+        continue
+      }
+      entry.Name = game.name
+      if game.steam.bundleId != 0 {
+        entry.pinned().Bundle = game.steam.bundleId
+      } else {
+        entry.pinned().Steam = game.steam.id
+      }
+    }
+
+    alreadyWatched := false
+    for _, criteria := range existing {
+      ids := entry.steamIds()
+      if criteria.owner != entry.Owner {
+        continue
+      }
+      if normalizeName(criteria.name) == normalizeName(entry.Name) || (ids != pinnedIds{} && criteria.pinned.steamId == ids.steamId && criteria.pinned.steamBundleId == ids.steamBundleId) {
+        alreadyWatched = true
+        break
+      }
+    }
+    if alreadyWatched {
+      fmt.Printf("\"%s\" is already watched\n", entry.Name)
+      continue
+    }
+
+    fmt.Printf("Adding \"%s\"\n", entry.Name)
+    entries = append(entries, entry)
+    existing = append(existing, newGameCriteria(entry.Name, defaultTarget))
+    existing[len(existing) - 1].pinned = entry.steamIds()
+    existing[len(existing) - 1].owner = entry.Owner
+  }
+
+  if len(entries) > 0 {
+    err = appendToWatchlist(*watchlistFile, entries)
+    if err != nil {
+      return err
+    }
+  }
+  if missing > 0 {
+    return fmt.Errorf("%d game(s) were not found on Steam", missing)
+  }
+  return nil
+}
+
+// watcher remove -file <watchlist> [-owner <owner>] <game>...
+func runRemove(args []string) error {
+  flags := flag.NewFlagSet("remove", flag.ExitOnError)
+  watchlistFile := flags.String("file", "", "Watchlist to remove from")
+  owner := flags.String("owner", "", "Only remove the games of this owner (all owners if empty)")
+  err := parseSubcommandFlags(flags, watchlistFile, args)
+  if err != nil {
+    return err
+  }
+
+  removed := make(map[string]bool)
+  err = editWatchlist(*watchlistFile, func(entry watchlistEntry) (watchlistEntry, bool) {
+    if *owner != "" && entry.Owner != *owner {
+      return entry, true
+    }
+    for _, name := range flags.Args() {
+      if entry.matches(name) {
+        fmt.Printf("Removing \"%s\"\n", entry.Name)
+        removed[name] = true
+        return entry, false
+      }
+    }
+    return entry, true
+  })
+  if err != nil {
+    return err
+  }
+
+  for _, name := range flags.Args() {
+    if !removed[name] {
+      fmt.Fprintf(os.Stderr, "\"%s\" is not in the watchlist\n", name)
+    }
+  }
+  return nil
+}
+
+// watcher set-target -file <watchlist> [-owner <owner>] <game> <target>
+func runSetTarget(args []string) error {
+  flags := flag.NewFlagSet("set-target", flag.ExitOnError)
+  watchlistFile := flags.String("file", "", "Watchlist to update")
+  owner := flags.String("owner", "", "Only update the game of this owner (all owners if empty)")
+  err := parseSubcommandFlags(flags, watchlistFile, args)
+  if err != nil {
+    return err
+  }
+  if flags.NArg() != 2 {
+    return errors.New("Expected a game and a target")
+  }
+  name := flags.Arg(0)
+  target := strings.TrimSpace(flags.Arg(1))
+  _, err = parseTarget(target)
+  if err != nil {
+    return err
+  }
+
+  updated := false
+  err = editWatchlist(*watchlistFile, func(entry watchlistEntry) (watchlistEntry, bool) {
+    if entry.matches(name) && (*owner == "" || entry.Owner == *owner) {
+      entry.Target = rawTarget(target)
+      updated = true
+    }
+    return entry, true
+  })
+  if err != nil {
+    return err
+  }
+  if !updated {
+    return fmt.Errorf("\"%s\" is not in the watchlist", name)
+  }
+  return nil
+}
+
+// watcher list -file <watchlist>
+func runList(args []string) error {
+  flags := flag.NewFlagSet("list", flag.ExitOnError)
+  watchlistFile := flags.String("file", "", "Watchlist to list")
+  err := parseSubcommandFlags(flags, watchlistFile, args)
+  if err != nil {
+    return err
+  }
+
+  criteria, err := parseWatchlistFile(*watchlistFile)
+  if err != nil {
+    return err
+  }
+  for _, criterium := range criteria {
+    details := []string{}
+    if len(criterium.stores) > 0 {
+      details = append(details, "stores: " + strings.Join(criterium.stores, ", "))
+    }
+    if criterium.edition != "" {
+      details = append(details, "edition: " + criterium.edition)
+    }
+    if len(criterium.tags) > 0 {
+      details = append(details, "tags: " + strings.Join(criterium.tags, ", "))
+    }
+    if criterium.priority != 0 {
+      details = append(details, fmt.Sprintf("priority: %d", criterium.priority))
+    }
+    if criterium.notes != "" {
+      details = append(details, "notes: " + criterium.notes)
+    }
+    if criterium.owner != "" {
+      details = append(details, "owner: " + criterium.owner)
+    }
+
+    fmt.Printf("%s: %v", criterium.name, criterium.target)
+    if len(details) > 0 {
+      fmt.Printf(" (%s)", strings.Join(details, ", "))
+    }
+    fmt.Printf("\n")
+  }
+  return nil
+}
+
+// Returns an editor dropping the entries of a game already seen for the same owner, counted in |removed|.
+// Entries pinned to different Steam ids are different games (see isSameGame) and
+// entries without name (pinned only, as imported from Steam) are only compared by ids.
+func newDedupeEditor(removed *int) entryEditor {
+  type ownedName struct {
+    owner string
+    name string
+  }
+  type ownedIds struct {
+    owner string
+    ids pinnedIds
+  }
+  seenIds := make(map[ownedIds]bool)
+  seenPinnedNames := make(map[ownedName]bool)
+  seenUnpinnedNames := make(map[ownedName]bool)
+  return func(entry watchlistEntry) (watchlistEntry, bool) {
+    name := ownedName{entry.Owner, normalizeName(entry.Name)}
+    ids := ownedIds{entry.Owner, entry.steamIds()}
+    hasIds := ids.ids != pinnedIds{}
+    hasName := name.name != ""
+
+    duplicate := hasIds && seenIds[ids]
+    if hasName && (seenUnpinnedNames[name] || (!hasIds && seenPinnedNames[name])) {
+      duplicate = true
+    }
+    if duplicate {
+      fmt.Printf("Removing duplicate \"%s\"\n", entry.Name)
+      *removed += 1
+      return entry, false
+    }
+
+    switch {
+      case hasIds:
+        seenIds[ids] = true
+        if hasName {
+          seenPinnedNames[name] = true
+        }
+      case hasName:
+        seenUnpinnedNames[name] = true
+    }
+    return entry, true
+  }
+}
+
+// watcher dedupe -file <watchlist>
+// The first entry of each owner for a game is kept.
+func runDedupe(args []string) error {
+  flags := flag.NewFlagSet("dedupe", flag.ExitOnError)
+  watchlistFile := flags.String("file", "", "Watchlist to dedupe")
+  err := parseSubcommandFlags(flags, watchlistFile, args)
+  if err != nil {
+    return err
+  }
+
+  removed := 0
+  err = editWatchlist(*watchlistFile, newDedupeEditor(&removed))
+  if err != nil {
+    return err
+  }
+  fmt.Printf("Removed %d duplicate(s)\n", removed)
+  return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+  "encoding/json"
+  "fmt"
+  "reflect"
+  "testing"
+)
+
+func TestDedupeEditor(t *testing.T) {
+  input := `{"games": [
+    {"pinned": {"steam": 1}},
+    {"pinned": {"steam": 2}},
+    {"pinned": {"steam": 1}},
+    {"name": "DOOM", "pinned": {"steam": 2280}},
+    {"name": "DOOM", "pinned": {"steam": 379720}},
+    {"name": "doom"},
+    {"name": "Foobar"},
+    {"name": "foobar", "pinned": {"steam": 10}},
+    {"name": "Foobar", "owner": "alice"}
+  ]}`
+  removed := 0
+  output, err := editJSONWatchlist([]byte(input), newDedupeEditor(&removed))
+  if err != nil {
+    t.Fatalf("Unexpected error %+v", err)
+  }
+
+  var document struct {
+    Games []watchlistEntry
+  }
+  err = json.Unmarshal(output, &document)
+  if err != nil {
+    t.Fatalf("Invalid output %s (err = %+v)", output, err)
+  }
+  kept := []string{}
+  for _, entry := range document.Games {
+    steamId := 0
+    if entry.Pinned != nil {
+      steamId = entry.Pinned.Steam
+    }
+    kept = append(kept, fmt.Sprintf("%s/%s/%d", entry.Name, entry.Owner, steamId))
+  }
+  // Pinned only entries are different games, as well as editions pinned to different ids.
+  expected := []string{"//1", "//2", "DOOM//2280", "DOOM//379720", "Foobar//0", "Foobar/alice/0"}
+  if !reflect.DeepEqual(kept, expected) || removed != 3 {
+    t.Errorf("Expected %+v but got %+v (%d removed)", expected, kept, removed)
+  }
+}
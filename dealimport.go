@@ -0,0 +1,361 @@
+package main
+
+import (
+  "bufio"
+  "bytes"
+  "encoding/csv"
+  "encoding/json"
+  "errors"
+  "flag"
+  "fmt"
+  "io"
+  "io/fs"
+  "os"
+  "path/filepath"
+  "strconv"
+  "strings"
+)
+
+// Importers for the exports of other deal trackers.
+// The waitlist exports of deal-aggregator sites are a list of games with
+// a title, an optional Steam app id, a target price and a note.
+// The column (CSV) and field (JSON) names vary across sites, see exportColumns.
+
+// Formats of the exports.
+const (
+  cExportWaitlistCSV = "waitlist-csv"
+  cExportWaitlistJSON = "waitlist-json"
+  // One Steam app id (or app/<appid>, bundle/<bundleid>, Steam store URL) per line.
+  cExportAppIds = "appids"
+)
+
+// Aliases of the export columns, keyed by watchlist field.
+// "price" isn't an alias of the target: it is the current price in most exports.
+var exportColumns = map[string][]string{
+  "name": []string{"title", "name", "game", "game_title"},
+  "steam": []string{"steam_appid", "steam_id", "steamid", "appid", "app_id", "steam"},
+  "target": []string{"target", "target_price", "price_target", "notify_price", "wanted_price"},
+  "notes": []string{"notes", "note", "comment", "comments"},
+  "tags": []string{"tags", "labels"},
+}
+
+// A game of a waitlist export: the values keyed by the lowercased column name.
+type exportRecord map[string]string
+
+// Returns the value of the first alias of |field| found in the record.
+func (record exportRecord) get(field string) string {
+  for _, alias := range exportColumns[field] {
+    if value := strings.TrimSpace(record[alias]); value != "" {
+      return value
+    }
+  }
+  return ""
+}
+
+// Converts an exported price ("9.99", "$9.99", "9,99 €", "1.299,99 €", "1,299.99") to a target expression.
+// Returns "" for missing or zero prices.
+func parseExportPrice(price string) (string, error) {
+  price = strings.Trim(price, " $€£")
+  if price == "" {
+    return "", nil
+  }
+  value, err := strconv.ParseFloat(normalizeDecimalSeparator(price), /*bitSize=*/32)
+  if err != nil || value < 0 {
+    return "", fmt.Errorf("Invalid price \"%s\"", price)
+  }
+  if value == 0 {
+    return "", nil
+  }
+  return strconv.FormatFloat(value, 'f', -1, /*bitSize=*/32), nil
+}
+
+// Returns |price| with '.' as decimal separator and without digit grouping.
+// With both '.' and ',', the last one is the decimal separator. A single separator
+// is taken as digit grouping when repeated or followed by 3 digits ("1.299", "1,299").
+func normalizeDecimalSeparator(price string) string {
+  price = strings.NewReplacer(" ", "", "\u00a0", "", "'", "").Replace(price)
+  decimal := strings.LastIndexAny(price, ".,")
+  if decimal < 0 {
+    return price
+  }
+  separator := price[decimal:decimal + 1]
+  singleSeparator := strings.Count(price, ".") == 0 || strings.Count(price, ",") == 0
+  if singleSeparator && (strings.Count(price, separator) > 1 || len(price) - decimal - 1 == 3) {
+    return strings.ReplaceAll(price, separator, "")
+  }
+  if strings.Count(price, separator) > 1 {
+    // Not a price, left for strconv.ParseFloat to reject.
+    return price
+  }
+  return strings.NewReplacer(".", "", ",", "").Replace(price[:decimal]) + "." + price[decimal + 1:]
+}
+
+// Parses a Steam app id given as a number or as a Steam reference.
+func parseExportSteamId(value string, entry *watchlistEntry) error {
+  id, err := strconv.Atoi(value)
+  if err == nil && id > 0 {
+    entry.pinned().Steam = id
+    return nil
+  }
+  ids, isReference := parseSteamReference(value)
+  if !isReference {
+    return fmt.Errorf("Invalid Steam app id \"%s\"", value)
+  }
+  entry.pinned().Steam = ids.steamId
+  entry.pinned().Bundle = ids.steamBundleId
+  return nil
+}
+
+func (record exportRecord) toEntry() (watchlistEntry, error) {
+  entry := watchlistEntry{Name: record.get("name"), Notes: record.get("notes")}
+  if steamId := record.get("steam"); steamId != "" {
+    err := parseExportSteamId(steamId, &entry)
+    if err != nil {
+      return watchlistEntry{}, err
+    }
+  }
+  if entry.Name == "" && entry.Pinned == nil {
+    return watchlistEntry{}, errors.New("No title nor Steam app id")
+  }
+
+  target, err := parseExportPrice(record.get("target"))
+  if err != nil {
+    return watchlistEntry{}, err
+  }
+  if target != "" {
+    entry.Target = rawTarget(target)
+  }
+
+  for _, tag := range strings.FieldsFunc(record.get("tags"), func(r rune) bool { return r == ',' || r == ';' }) {
+    if tag = strings.TrimSpace(tag); tag != "" {
+      entry.Tags = append(entry.Tags, tag)
+    }
+  }
+  return entry, nil
+}
+
+// The first row is the header naming the columns.
+func parseWaitlistCSV(reader io.Reader) ([]watchlistEntry, error) {
+  csvReader := csv.NewReader(reader)
+  csvReader.FieldsPerRecord = -1
+  csvReader.TrimLeadingSpace = true
+  header, err := csvReader.Read()
+  if err != nil {
+    return nil, fmt.Errorf("Missing header (err = %+v)", err)
+  }
+  for idx := range header {
+    header[idx] = strings.ToLower(strings.TrimSpace(header[idx]))
+  }
+
+  entries := []watchlistEntry{}
+  for {
+    row, err := csvReader.Read()
+    if err == io.EOF {
+      break
+    }
+    if err != nil {
+      return nil, err
+    }
+    if isBlankRecord(row) {
+      continue
+    }
+    line, _ := csvReader.FieldPos(0)
+
+    record := exportRecord{}
+    for idx, value := range row {
+      if idx < len(header) {
+        record[header[idx]] = value
+      }
+    }
+    entry, err := record.toEntry()
+    if err != nil {
+      return nil, fmt.Errorf("line %d: %v", line, err)
+    }
+    entries = append(entries, entry)
+  }
+  return entries, nil
+}
+
+// Converts a JSON value to its string form: strings are unquoted, lists are joined with ';'.
+func exportValue(raw json.RawMessage) string {
+  var value string
+  if json.Unmarshal(raw, &value) == nil {
+    return value
+  }
+  var values []string
+  if json.Unmarshal(raw, &values) == nil {
+    return strings.Join(values, ";")
+  }
+  var number json.Number
+  if json.Unmarshal(raw, &number) == nil {
+    return number.String()
+  }
+  // Objects and null.
+  return ""
+}
+
+// The games are either a top level array or under "data", "games" or "waitlist".
+func parseWaitlistJSON(data []byte) ([]watchlistEntry, error) {
+  var items []map[string]json.RawMessage
+  if json.Unmarshal(data, &items) != nil {
+    var document map[string]json.RawMessage
+    err := json.Unmarshal(data, &document)
+    if err != nil {
+      return nil, err
+    }
+    found := false
+    for _, key := range []string{"data", "games", "waitlist"} {
+      if rawItems, hasKey := document[key]; hasKey {
+        err = json.Unmarshal(rawItems, &items)
+        if err != nil {
+          return nil, fmt.Errorf("Invalid \"%s\" (err = %+v)", key, err)
+        }
+        found = true
+        break
+      }
+    }
+    if !found {
+      return nil, errors.New("No \"data\", \"games\" or \"waitlist\" list")
+    }
+  }
+
+  entries := []watchlistEntry{}
+  for idx, item := range items {
+    record := exportRecord{}
+    for key, value := range item {
+      record[strings.ToLower(key)] = exportValue(value)
+    }
+    entry, err := record.toEntry()
+    if err != nil {
+      return nil, fmt.Errorf("game %d: %v", idx + 1, err)
+    }
+    entries = append(entries, entry)
+  }
+  return entries, nil
+}
+
+// Blank lines and lines starting with '#' are ignored.
+func parseAppIdList(reader io.Reader) ([]watchlistEntry, error) {
+  entries := []watchlistEntry{}
+  scanner := bufio.NewScanner(reader)
+  line := 0
+  for scanner.Scan() {
+    line += 1
+    value := strings.TrimSpace(scanner.Text())
+    if value == "" || strings.HasPrefix(value, "#") {
+      continue
+    }
+    entry := watchlistEntry{}
+    err := parseExportSteamId(value, &entry)
+    if err != nil {
+      return nil, fmt.Errorf("line %d: %v", line, err)
+    }
+    entries = append(entries, entry)
+  }
+  return entries, scanner.Err()
+}
+
+// Guesses the format from the file extension.
+func exportFormat(fileName string) string {
+  switch strings.ToLower(filepath.Ext(fileName)) {
+    case ".json":
+      return cExportWaitlistJSON
+    case ".csv":
+      return cExportWaitlistCSV
+  }
+  return cExportAppIds
+}
+
+func parseExport(data []byte, format string) ([]watchlistEntry, error) {
+  switch format {
+    case cExportWaitlistCSV:
+      return parseWaitlistCSV(bytes.NewReader(data))
+    case cExportWaitlistJSON:
+      return parseWaitlistJSON(data)
+    case cExportAppIds:
+      return parseAppIdList(bytes.NewReader(data))
+  }
+  return nil, fmt.Errorf("Unknown format \"%s\"", format)
+}
+
+// Drops the entries already in |existing| (or imported earlier).
+func dropWatchedEntries(entries []watchlistEntry, existing []gameCriteria) ([]watchlistEntry, int) {
+  kept := []watchlistEntry{}
+  skipped := 0
+  for _, entry := range entries {
+    criteria, err := entry.toCriteria(defaultTarget)
+    if err != nil {
+      // Invalid entries are reported when the watchlist is read.
+      kept = append(kept, entry)
+      continue
+    }
+
+    watched := false
+    for _, existingCriteria := range existing {
+      if isSameGame(existingCriteria, criteria) {
+        watched = true
+        break
+      }
+    }
+    if watched {
+      skipped += 1
+      continue
+    }
+    kept = append(kept, entry)
+    existing = append(existing, criteria)
+  }
+  return kept, skipped
+}
+
+// watcher import -file <watchlist> [-format <format>] <export>...
+func runImport(args []string) error {
+  flags := flag.NewFlagSet("import", flag.ExitOnError)
+  watchlistFile := flags.String("file", "", "Watchlist to merge into (created if missing)")
+  format := flags.String("format", "", fmt.Sprintf("Format of the exports: %s, %s or %s (guessed from the extension if empty)", cExportWaitlistCSV, cExportWaitlistJSON, cExportAppIds))
+  err := parseSubcommandFlags(flags, watchlistFile, args)
+  if err != nil {
+    return err
+  }
+  if flags.NArg() == 0 {
+    flags.Usage()
+    return errors.New("No export to import")
+  }
+
+  entries := []watchlistEntry{}
+  for _, exportFile := range flags.Args() {
+    data, err := os.ReadFile(exportFile)
+    if err != nil {
+      return err
+    }
+    exportFileFormat := *format
+    if exportFileFormat == "" {
+      exportFileFormat = exportFormat(exportFile)
+    }
+    exportEntries, err := parseExport(data, exportFileFormat)
+    if err != nil {
+      return fmt.Errorf("Invalid export=%s (err = %+v)", exportFile, err)
+    }
+    entries = append(entries, exportEntries...)
+  }
+
+  existing := []gameCriteria{}
+  _, err = os.Stat(*watchlistFile)
+  if err == nil {
+    existing, err = parseWatchlistFile(*watchlistFile)
+    if err != nil {
+      return err
+    }
+  } else if !errors.Is(err, fs.ErrNotExist) {
+    return err
+  }
+
+  entries, skipped := dropWatchedEntries(entries, existing)
+  if len(entries) > 0 {
+    err = appendToWatchlist(*watchlistFile, entries)
+    if err != nil {
+      return err
+    }
+  }
+  fmt.Printf("Added %d game(s) to %s (skipped %d already watched)\n", len(entries), *watchlistFile, skipped)
+  return nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+  "reflect"
+  "strings"
+  "testing"
+)
+
+func TestParseWaitlistCSV(t *testing.T) {
+  input := `Title,Steam_AppID,Target_Price,Note
+"Foo, The",10,$9.99,Wait for the sale
+Bar,,"4,50",
+,20,,
+`
+  entries, err := parseWaitlistCSV(strings.NewReader(input))
+  if err != nil {
+    t.Fatalf("Unexpected error %+v", err)
+  }
+
+  expected := []watchlistEntry{
+    watchlistEntry{Name: "Foo, The", Target: rawTarget("9.99"), Pinned: &watchlistPinnedIds{Steam: 10}, Notes: "Wait for the sale"},
+    watchlistEntry{Name: "Bar", Target: rawTarget("4.5")},
+    watchlistEntry{Pinned: &watchlistPinnedIds{Steam: 20}},
+  }
+  if !reflect.DeepEqual(entries, expected) {
+    t.Errorf("Expected %+v but got %+v", expected, entries)
+  }
+
+  _, err = parseWaitlistCSV(strings.NewReader("title,target\nFoo,1\nBar,cheap\n"))
+  if err == nil || !strings.Contains(err.Error(), "line 3: Invalid price") {
+    t.Errorf("Expected an error on line 3 but got %+v", err)
+  }
+}
+
+func TestParseExportPrice(t *testing.T) {
+  tt := []struct {
+    price string
+    expected string
+  } {
+    {"9.99", "9.99"},
+    {"$9.99", "9.99"},
+    {"9,99 €", "9.99"},
+    {"1.299,99 €", "1299.99"},
+    {"1,299.99", "1299.99"},
+    {"1 299,99", "1299.99"},
+    {"1.299", "1299"},
+    {"1.299.000", "1299000"},
+    {"0", ""},
+    {"", ""},
+  }
+
+  for _, tc := range(tt) {
+    price, err := parseExportPrice(tc.price)
+    if err != nil || price != tc.expected {
+      t.Errorf("Expected %s for %s but got %s (err = %+v)", tc.expected, tc.price, price, err)
+    }
+  }
+  if _, err := parseExportPrice("1,2,3.4.5"); err == nil {
+    t.Errorf("Expected an error for mixed repeated separators")
+  }
+}
+
+func TestParseWaitlistJSON(t *testing.T) {
+  tt := []struct {
+    name string
+    input string
+  } {
+    {"Array", `[{"title": "Foo", "steam_appid": 10, "target": 5, "note": "Coop", "tags": ["coop"]}, {"title": "Bar", "shops": {"steam": 1}}]`},
+    {"Data", `{"version": 2, "data": [{"Title": "Foo", "appid": "app/10", "price": "$9", "notify_price": "$5", "notes": "Coop", "tags": "coop"}, {"title": "Bar", "notify_price": null}]}`},
+  }
+
+  expected := []watchlistEntry{
+    watchlistEntry{Name: "Foo", Target: rawTarget("5"), Pinned: &watchlistPinnedIds{Steam: 10}, Notes: "Coop", Tags: []string{"coop"}},
+    watchlistEntry{Name: "Bar"},
+  }
+  for _, tc := range(tt) {
+    t.Run(tc.name, func(t *testing.T) {
+      entries, err := parseWaitlistJSON([]byte(tc.input))
+      if err != nil {
+        t.Fatalf("Unexpected error %+v", err)
+      }
+      if !reflect.DeepEqual(entries, expected) {
+        t.Errorf("Expected %+v but got %+v", expected, entries)
+      }
+    })
+  }
+}
+
+func TestParseAppIdList(t *testing.T) {
+  input := "# Exported\n10\n\napp/20\nhttps://store.steampowered.com/bundle/30/Foo/\n"
+  entries, err := parseAppIdList(strings.NewReader(input))
+  if err != nil {
+    t.Fatalf("Unexpected error %+v", err)
+  }
+  expected := []watchlistEntry{
+    watchlistEntry{Pinned: &watchlistPinnedIds{Steam: 10}},
+    watchlistEntry{Pinned: &watchlistPinnedIds{Steam: 20}},
+    watchlistEntry{Pinned: &watchlistPinnedIds{Bundle: 30}},
+  }
+  if !reflect.DeepEqual(entries, expected) {
+    t.Errorf("Expected %+v but got %+v", expected, entries)
+  }
+
+  _, err = parseAppIdList(strings.NewReader("10\nFoo\n"))
+  if err == nil || !strings.Contains(err.Error(), "line 2") {
+    t.Errorf("Expected an error on line 2 but got %+v", err)
+  }
+}
+
+func TestDropWatchedEntries(t *testing.T) {
+  existing := []gameCriteria{
+    newGameCriteria("app/10", defaultTarget),
+    newGameCriteria("Watched", defaultTarget),
+  }
+  entries := []watchlistEntry{
+    watchlistEntry{Pinned: &watchlistPinnedIds{Steam: 10}},
+    watchlistEntry{Name: "watched"},
+    watchlistEntry{Name: "New", Pinned: &watchlistPinnedIds{Steam: 20}},
+    watchlistEntry{Pinned: &watchlistPinnedIds{Steam: 20}},
+  }
+
+  kept, skipped := dropWatchedEntries(entries, existing)
+  if !reflect.DeepEqual(kept, entries[2:3]) || skipped != 3 {
+    t.Errorf("Expected to only keep %+v but got %+v (skipped %d)", entries[2], kept, skipped)
+  }
+}
@@ -0,0 +1,31 @@
+package main
+
+// Exit codes, for scripts gating on the run (same convention as grep).
+const (
+  cExitOK = 0
+  // Some games are under target.
+  cExitMatches = cExitOK
+  cExitNoMatches = 1
+  // Invalid flags or watchlist, or a file that couldn't be read or written.
+  // This is also the code of the flag package on invalid flags.
+  cExitFatal = 2
+  // Some games or stores couldn't be fetched (network or HTTP failures), whether or not games are under target.
+  cExitPartialFailure = 3
+)
+
+// Games that don't exist (mistyped names) are input warnings and don't change the code:
+// a watchlist with a typo would otherwise always exit with cExitPartialFailure.
+func exitCode(output *Output) int {
+  if len(output.backendErrors) > 0 {
+    return cExitPartialFailure
+  }
+  for _, gameError := range output.errors {
+    if !gameError.notFound {
+      return cExitPartialFailure
+    }
+  }
+  if len(output.matchingGames) > 0 {
+    return cExitMatches
+  }
+  return cExitNoMatches
+}
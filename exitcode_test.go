@@ -0,0 +1,60 @@
+package main
+
+import (
+  "bytes"
+  "testing"
+)
+
+func TestExitCode(t *testing.T) {
+  tt := []struct {
+    name string
+    matching bool
+    failed bool
+    notFound bool
+    expected int
+  } {
+    {"No matches", false, false, false, cExitNoMatches},
+    {"Matches", true, false, false, cExitMatches},
+    {"Partial failure", false, true, false, cExitPartialFailure},
+    {"Partial failure with matches", true, true, false, cExitPartialFailure},
+    {"Not found", false, false, true, cExitNoMatches},
+    {"Not found with matches", true, false, true, cExitMatches},
+    {"Not found and partial failure", true, true, true, cExitPartialFailure},
+  }
+
+  for _, tc := range(tt) {
+    t.Run(tc.name, func(t *testing.T) {
+      output := newOutput()
+      if tc.matching {
+        output.matchingGames = []Game{pricedGame(4.99, -1)}
+      }
+      if tc.failed {
+        output.errors = append(output.errors, gameError{gameCriteria{name: "Unreachable"}, "Unexpected HTTP status 503", false})
+      }
+      if tc.notFound {
+        output.errors = append(output.errors, gameError{gameCriteria{name: "Missing"}, "No matches", true})
+      }
+      if code := exitCode(&output); code != tc.expected {
+        t.Errorf("Expected %d but got %d", tc.expected, code)
+      }
+    })
+  }
+}
+
+func TestExitCodeBackendFailure(t *testing.T) {
+  output := newOutput()
+  output.matchingGames = []Game{pricedGame(4.99, -1)}
+  output.backendErrors = []string{"fanatical: Unexpected HTTP status 503"}
+  if code := exitCode(&output); code != cExitPartialFailure {
+    t.Errorf("Expected %d but got %d", cExitPartialFailure, code)
+  }
+}
+
+func TestPrintMatchingGames(t *testing.T) {
+  var buffer bytes.Buffer
+  printMatchingGames(&buffer, newTestReportOutput().matchingGames)
+  expected := "Foobar: $4.99 - https://www.fanatical.com/en/game/foobar (under $5.00) for alice\n"
+  if buffer.String() != expected {
+    t.Errorf("Expected %q but got %q", expected, buffer.String())
+  }
+}
@@ -2,6 +2,7 @@ package main
 
 import (
   "encoding/json"
+  "errors"
   "io"
   "fmt"
   "net/http"
@@ -33,6 +34,10 @@ func InitFanatical() error {
     return err
   }
   defer resp.Body.Close()
+  err = checkHTTPStatus(resp)
+  if err != nil {
+    return err
+  }
   body, err := io.ReadAll(resp.Body)
   if err != nil {
     return err
@@ -46,7 +51,7 @@ func InitFanatical() error {
   // Note: We ignore the ValidUntil field as we should process all entries within the lifetime of the key.
   fanaticalKey = parsedResp.Key
   if fanaticalKey == "" {
-    panic("Invalid search key for Fanatical")
+    return errors.New("Invalid search key for Fanatical")
   }
 
   return nil
@@ -68,6 +73,21 @@ type fanaticalSearchResponse struct {
 }
 
 func FillFanaticalInfo(game *Game) error {
+  if game.criteria.pinned.fanaticalSlug != "" {
+    pinned := Game{fanatical: FanaticalInfo{-1, game.criteria.pinned.fanaticalSlug}}
+    err, price := fetchProductPagePrice(pinned.fanaticalURL())
+    if err != nil {
+      return err
+    }
+    game.fanatical = FanaticalInfo{price, pinned.fanatical.slug}
+    return nil
+  }
+
+  // InitFanatical failed, which is reported once for the run.
+  if fanaticalKey == "" {
+    return nil
+  }
+
   searchURL := fmt.Sprintf(cFanaticalSearchURLMissingKey, fanaticalKey)
   if debugFlag {
     fmt.Printf("Fanatical search URL: \"%s\"\n", searchURL)
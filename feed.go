@@ -0,0 +1,122 @@
+package main
+
+import (
+  "encoding/xml"
+  "errors"
+  "fmt"
+  "io/fs"
+  "os"
+  "time"
+)
+
+// Atom feed of the games under target (-feed).
+//
+// Each run adds its new deals to the feed file: the entry ids are built from the
+// Steam id, store and price so a deal already in the feed isn't added again and
+// a new price (or store) gets a new entry that readers show as unread.
+
+const cFeedId = "urn:steamgamewatcher:deals"
+
+// The oldest entries are dropped past this.
+const cFeedMaxEntries = 200
+
+type atomPerson struct {
+  Name string `xml:"name"`
+}
+
+type atomLink struct {
+  Href string `xml:"href,attr"`
+}
+
+type atomEntry struct {
+  ID string `xml:"id"`
+  Title string `xml:"title"`
+  Link atomLink `xml:"link"`
+  Updated string `xml:"updated"`
+  Summary string `xml:"summary"`
+}
+
+type atomFeed struct {
+  XMLName xml.Name `xml:"http://www.w3.org/2005/Atom feed"`
+  ID string `xml:"id"`
+  Title string `xml:"title"`
+  Updated string `xml:"updated"`
+  Author atomPerson `xml:"author"`
+  Entries []atomEntry `xml:"entry"`
+}
+
+// Returns "urn:steamgamewatcher:app/12345:fanatical:4.99", suffixed by the owner for shared watchlists.
+func feedEntryId(game Game) string {
+  id := fmt.Sprintf("urn:steamgamewatcher:%s:%s:%.2f", game.steamKey(), game.backend, game.minPrice)
+  if game.criteria.owner != "" {
+    id += ":" + game.criteria.owner
+  }
+  return id
+}
+
+func newFeedEntry(game Game, now time.Time) atomEntry {
+  title := fmt.Sprintf("%s: $%.2f on %s%s", game.name, game.minPrice, storeTitles[game.backend], game.criteria.ownerSuffix())
+  summary := fmt.Sprintf("%s (target %s)", game.matchedCondition, game.criteria.target)
+  return atomEntry{feedEntryId(game), title, atomLink{game.url()}, now.UTC().Format(time.RFC3339), summary}
+}
+
+// A missing file is an empty feed.
+func loadFeed(fileName string) (atomFeed, error) {
+  feed := atomFeed{}
+  data, err := os.ReadFile(fileName)
+  if errors.Is(err, fs.ErrNotExist) {
+    return feed, nil
+  }
+  if err != nil {
+    return feed, err
+  }
+  err = xml.Unmarshal(data, &feed)
+  return feed, err
+}
+
+// Adds the games under target that aren't in |feed| yet, newest first.
+func updateFeed(feed atomFeed, games []Game, now time.Time) atomFeed {
+  known := make(map[string]bool)
+  for _, entry := range feed.Entries {
+    known[entry.ID] = true
+  }
+
+  newEntries := []atomEntry{}
+  for _, game := range games {
+    entry := newFeedEntry(game, now)
+    if known[entry.ID] {
+      continue
+    }
+    known[entry.ID] = true
+    newEntries = append(newEntries, entry)
+  }
+
+  feed.ID = cFeedId
+  feed.Title = "Game deals"
+  feed.Author = atomPerson{"steamgamewatcher"}
+  if len(newEntries) > 0 || feed.Updated == "" {
+    feed.Updated = now.UTC().Format(time.RFC3339)
+  }
+  feed.Entries = append(newEntries, feed.Entries...)
+  if len(feed.Entries) > cFeedMaxEntries {
+    feed.Entries = feed.Entries[:cFeedMaxEntries]
+  }
+  return feed
+}
+
+func saveFeed(fileName string, feed atomFeed) error {
+  data, err := xml.MarshalIndent(feed, "", "  ")
+  if err != nil {
+    return err
+  }
+  return os.WriteFile(fileName, append([]byte(xml.Header), append(data, '\n')...), 0644)
+}
+
+// Updates the -feed file with the games under target.
+func writeFeed(fileName string, games []Game, now time.Time) error {
+  feed, err := loadFeed(fileName)
+  if err != nil {
+    return err
+  }
+  return saveFeed(fileName, updateFeed(feed, games, now))
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+  "os"
+  "path/filepath"
+  "reflect"
+  "strings"
+  "testing"
+  "time"
+)
+
+func TestFeedEntryId(t *testing.T) {
+  game := newTestReportOutput().matchingGames[0]
+  expected := "urn:steamgamewatcher:app/10:fanatical:4.99:alice"
+  if id := feedEntryId(game); id != expected {
+    t.Errorf("Expected %s but got %s", expected, id)
+  }
+}
+
+func TestWriteFeed(t *testing.T) {
+  fileName := filepath.Join(t.TempDir(), "deals.atom")
+  game := newTestReportOutput().matchingGames[0]
+  firstRun := time.Date(2026, 10, 17, 8, 0, 0, 0, time.UTC)
+  secondRun := firstRun.Add(24 * time.Hour)
+
+  ids := func() []string {
+    feed, err := loadFeed(fileName)
+    if err != nil {
+      t.Fatalf("Error loading the feed (err = %+v)", err)
+    }
+    ids := []string{}
+    for _, entry := range feed.Entries {
+      ids = append(ids, entry.ID)
+    }
+    return ids
+  }
+
+  err := writeFeed(fileName, []Game{game}, firstRun)
+  if err != nil {
+    t.Fatalf("Error writing the feed (err = %+v)", err)
+  }
+  // The same deal isn't added twice.
+  err = writeFeed(fileName, []Game{game}, secondRun)
+  if err != nil {
+    t.Fatalf("Error writing the feed (err = %+v)", err)
+  }
+  expected := []string{"urn:steamgamewatcher:app/10:fanatical:4.99:alice"}
+  if got := ids(); !reflect.DeepEqual(got, expected) {
+    t.Errorf("Expected %+v but got %+v", expected, got)
+  }
+
+  // A lower price is a new deal, added first.
+  game.fanatical.price = 3.99
+  fillMinPrice(&game)
+  err = writeFeed(fileName, []Game{game}, secondRun)
+  if err != nil {
+    t.Fatalf("Error writing the feed (err = %+v)", err)
+  }
+  expected = append([]string{"urn:steamgamewatcher:app/10:fanatical:3.99:alice"}, expected...)
+  if got := ids(); !reflect.DeepEqual(got, expected) {
+    t.Errorf("Expected %+v but got %+v", expected, got)
+  }
+
+  data, err := os.ReadFile(fileName)
+  if err != nil {
+    t.Fatalf("Error reading the feed (err = %+v)", err)
+  }
+  for _, fragment := range []string{`<feed xmlns="http://www.w3.org/2005/Atom">`, `<updated>2026-10-18T08:00:00Z</updated>`, `<title>Foobar: $3.99 on Fanatical for alice</title>`, `<link href="https://www.fanatical.com/en/game/foobar"></link>`} {
+    if !strings.Contains(string(data), fragment) {
+      t.Errorf("Expected %s in the feed:\n%s", fragment, data)
+    }
+  }
+}
@@ -2,6 +2,13 @@ package main
 
 import (
   "strings"
+  "unicode"
+
+  "golang.org/x/text/cases"
+  "golang.org/x/text/runes"
+  "golang.org/x/text/transform"
+  "golang.org/x/text/unicode/norm"
+  "golang.org/x/text/width"
 )
 
 type GenericGame struct {
@@ -39,6 +46,50 @@ func shouldIgnore(game GenericGame) bool {
   return false
 }
 
+// Punctuation variants that stores use interchangeably.
+var punctuationReplacer = strings.NewReplacer(
+  "\u2018", "'", "\u2019", "'",
+  "\u201C", "\"", "\u201D", "\"",
+  "\u2013", "-", "\u2014", "-",
+)
+
+// Folds the unicode variants of a name:
+// * fullwidth/halfwidth forms are mapped to their canonical width,
+// * diacritics of Latin letters are removed (é -> e),
+// * symbols like ™ or ® are dropped,
+// * typographic punctuation is mapped to ASCII.
+// The combining marks of the other scripts are kept: they change the letter
+// (ガ is カ with a dakuten, स्ते loses its vowel sign and virama).
+// Only used for matching, the stores are searched with the original name.
+func foldUnicode(name string) string {
+  t := transform.Chain(width.Fold, norm.NFD, runes.Remove(runes.In(unicode.So)))
+  decomposed, _, err := transform.String(t, name)
+  if err != nil {
+    return name
+  }
+
+  folded := []rune{}
+  latinBase := false
+  for _, r := range decomposed {
+    if unicode.Is(unicode.Mn, r) {
+      if latinBase {
+        continue
+      }
+    } else {
+      latinBase = unicode.Is(unicode.Latin, r)
+    }
+    folded = append(folded, r)
+  }
+  result := punctuationReplacer.Replace(norm.NFC.String(string(folded)))
+  // Dropping symbols can leave double spaces behind.
+  return strings.Join(strings.Fields(result), " ")
+}
+
+// Folds unicode variants and case.
+func normalizeName(name string) string {
+  return cases.Fold().String(foldUnicode(name))
+}
+
 // Remove some keywords and lowers the string.
 func normalizeResult(result string) string {
   normalized := result
@@ -52,21 +103,22 @@ func normalizeResult(result string) string {
     normalized = before + strings.TrimSpace(after)
   }
 
-  return strings.ToLower(normalized)
+  return normalizeName(normalized)
 }
 
 // Returns a number from 0.0 (no match) to 1.0 (perfect match) to represent
 // the potential of the current result.
 func score(name, result string) float32 {
   normalized := normalizeResult(result)
-  if strings.ToLower(name) == normalized {
+  // The keywords are kept when looking for a specific edition (e.g. "Foobar Deluxe Edition").
+  if normalizeName(name) == normalized || normalizeName(name) == normalizeName(result) {
     // Direct match.
     return 1.0
   }
 
   // We should allow some looser comparison here that:
   // 1. Ignore punctionations (e.g. dashes, colons, ...)
-  // 2. Ignore non-ascii characters (e.g. TM, ...)
+  // 2. Ignore non-ascii characters (e.g. TM, ...) -> Done by normalizeName.
 
   // TODO: Make this smarter :)
   return 0.0
@@ -1,6 +1,9 @@
 package main
 
 import (
+  "encoding/json"
+  "fmt"
+  "os"
   "testing"
 )
 
@@ -35,6 +38,15 @@ func TestBestMatch(t *testing.T) {
 
     {"Prefers base game rather than Deluxe", "Foobar", []GenericGame{gg("Foobar"), gg("Foobar Deluxe")}, 0},
     {"Prefers base game rather than DLC", "Foobar", []GenericGame{gg("Foobar - extra content"), gg("Foobar")}, 1},
+
+    {"Ignores case", "FOOBAR", []GenericGame{gg("Foobar")}, 0},
+    {"Ignores diacritics", "Föóbàr", []GenericGame{gg("Foobar")}, 0},
+    {"Ignores width", "Ｆｏｏｂａｒ", []GenericGame{gg("Foobar")}, 0},
+    {"Ignores symbols", "Foobar™", []GenericGame{gg("Foobar®")}, 0},
+    {"Ignores typographic punctuation", "Foo’s Bar – Remastered", []GenericGame{gg("Foo's Bar - Remastered")}, 0},
+    {"Matches non-Latin names", "大神", []GenericGame{gg("Okami"), gg("大神")}, 1},
+    {"Keeps dakuten", "ガンダム", []GenericGame{gg("カンタム"), gg("ガンダム")}, 1},
+    {"Keeps Devanagari marks", "नमस्ते", []GenericGame{gg("नमसत"), gg("नमस्ते")}, 1},
   }
 
   for _, tc := range(tt) {
@@ -47,3 +59,132 @@ func TestBestMatch(t *testing.T) {
     })
   }
 }
+
+func TestFoldUnicode(t *testing.T) {
+  tt := []struct {
+    name string
+    input string
+    expected string
+  } {
+    {"Latin diacritics", "Pokémon Café", "Pokemon Cafe"},
+    {"Dakuten", "ガンダム", "ガンダム"},
+    {"Handakuten", "ポケモン", "ポケモン"},
+    {"Kana with Latin", "ドラゴンクエスト Héroes", "ドラゴンクエスト Heroes"},
+    {"Halfwidth kana", "ｶﾞﾝﾀﾞﾑ", "ガンダム"},
+    {"Devanagari", "नमस्ते", "नमस्ते"},
+    {"Symbols", "Foobar™ ®", "Foobar"},
+  }
+
+  for _, tc := range(tt) {
+    t.Run(tc.name, func(t *testing.T) {
+      if folded := foldUnicode(tc.input); folded != tc.expected {
+        t.Errorf("Expected %q but got %q", tc.expected, folded)
+      }
+    })
+  }
+}
+
+const cMatchCorpusFile string = "testdata/match_corpus.json"
+
+type corpusHit struct {
+  Name string
+  Price float32
+}
+
+type corpusCase struct {
+  // Watchlist name as returned by Steam.
+  Name string
+  // Store the hits come from (informational).
+  Store string
+  Hits []corpusHit
+  // Name of the hit that should be picked, empty if none should.
+  Expected string
+  // Set for cases the matcher currently gets wrong.
+  // Those are reported but don't fail the test.
+  KnownFailure bool
+  // Set for hits recorded from the store responses, the others are synthetic.
+  Recorded bool
+}
+
+type corpusFile struct {
+  // Where the cases come from.
+  Source string
+  Cases []corpusCase
+}
+
+// Runs BestMatch over the whole corpus and reports precision/recall.
+// Use `make match-report` to see the full report.
+func TestMatchCorpus(t *testing.T) {
+  data, err := os.ReadFile(cMatchCorpusFile)
+  if err != nil {
+    t.Fatalf("Couldn't read corpus (err = %+v)", err)
+  }
+  var file corpusFile
+  err = json.Unmarshal(data, &file)
+  if err != nil {
+    t.Fatalf("Couldn't parse corpus (err = %+v)", err)
+  }
+  corpus := file.Cases
+  recorded := 0
+  for _, c := range(corpus) {
+    if c.Recorded {
+      recorded += 1
+    }
+  }
+
+  // Precision is over the picked hits, recall over the cases that have an expected hit.
+  truePositives := 0
+  falsePositives := 0
+  expectedMatches := 0
+  regressions := []string{}
+  fixed := []string{}
+  for _, c := range(corpus) {
+    results := []GenericGame{}
+    for _, hit := range(c.Hits) {
+      results = append(results, GenericGame{hit.Name, hit.Price, ""})
+    }
+
+    matched := ""
+    bestMatchIdx := BestMatch(c.Name, results)
+    if bestMatchIdx != -1 {
+      matched = results[bestMatchIdx].name
+      if matched == c.Expected {
+        truePositives += 1
+      } else {
+        falsePositives += 1
+      }
+    }
+    if c.Expected != "" {
+      expectedMatches += 1
+    }
+
+    description := fmt.Sprintf("[%s] \"%s\": expected \"%s\", got \"%s\"", c.Store, c.Name, c.Expected, matched)
+    if matched != c.Expected && !c.KnownFailure {
+      regressions = append(regressions, description)
+    }
+    if matched == c.Expected && c.KnownFailure {
+      fixed = append(fixed, description)
+    }
+  }
+
+  precision := float32(1.0)
+  if truePositives + falsePositives > 0 {
+    precision = float32(truePositives) / float32(truePositives + falsePositives)
+  }
+  recall := float32(1.0)
+  if expectedMatches > 0 {
+    recall = float32(truePositives) / float32(expectedMatches)
+  }
+  t.Logf("%d cases (%d recorded from the stores, %d synthetic, %d expecting no match)", len(corpus), recorded, len(corpus) - recorded, len(corpus) - expectedMatches)
+  if recorded == 0 {
+    t.Logf("No recorded store response: the figures below only reflect the synthetic fixtures")
+  }
+  t.Logf("%d cases: precision = %.2f (%d/%d), recall = %.2f (%d/%d)", len(corpus), precision, truePositives, truePositives + falsePositives, recall, truePositives, expectedMatches)
+
+  for _, description := range(fixed) {
+    t.Logf("Fixed (drop knownFailure): %s", description)
+  }
+  for _, description := range(regressions) {
+    t.Errorf("Regression: %s", description)
+  }
+}
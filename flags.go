@@ -1,5 +1,45 @@
 package main
 
+import (
+  "errors"
+  "strings"
+)
+
+// Repeated -file flags.
+type fileList []string
+
+func (files *fileList) String() string {
+  return strings.Join(*files, ",")
+}
+
+// Stdin can only be read once.
+func (files *fileList) Set(fileName string) error {
+  if fileName == "-" {
+    for _, file := range *files {
+      if file == "-" {
+        return errors.New("- (stdin) can only be given once")
+      }
+    }
+  }
+  *files = append(*files, fileName)
+  return nil
+}
+
 var debugFlag bool
 var gamesFlag string
-var fileFlag string
+var fileFlag fileList
+var duplicatesFlag string
+var targetFlag string
+var historyFlag string
+var tagsFlag string
+var budgetFlag float64
+var formatFlag string
+var wideFlag bool
+var templateFlag string
+var snapshotFlag string
+var sinceLastFlag bool
+var quietFlag bool
+var feedFlag string
+var colorFlag string
+var hyperlinksFlag bool
+var collapseFlag bool
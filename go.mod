@@ -2,4 +2,7 @@ module steamgamewatcher
 
 go 1.14
 
-require golang.org/x/net v0.0.0-20200506145744-7e3656a0809f // indirect
+require (
+	golang.org/x/net v0.0.0-20200506145744-7e3656a0809f
+	golang.org/x/text v0.3.7
+)
@@ -47,6 +47,16 @@ type gmgSearchResponse struct {
 }
 
 func FillGreenManGamingInfo(game *Game) error {
+  if game.criteria.pinned.gmgPath != "" {
+    pinned := Game{gmg: GreenManGamingInfo{-1, game.criteria.pinned.gmgPath}}
+    err, price := fetchProductPagePrice(pinned.greenManGamingURL())
+    if err != nil {
+      return err
+    }
+    game.gmg = GreenManGamingInfo{price, pinned.gmg.path}
+    return nil
+  }
+
   searchURL := fmt.Sprintf(cGMGSearchURLMissingKey, cGMGApiKey)
   if debugFlag {
     fmt.Printf("GreenManGaming search URL: \"%s\"\n", searchURL)
@@ -0,0 +1,80 @@
+package main
+
+import (
+  "encoding/json"
+  "errors"
+  "io/fs"
+  "os"
+  "sync"
+  "time"
+)
+
+type historicalLow struct {
+  Price float32
+  Backend string
+  // Day of the run that saw the price (YYYY-MM-DD).
+  Date string
+}
+
+// Lowest price seen for each game across runs.
+// It is loaded from and saved to the -history file.
+type priceHistory struct {
+  // Keyed by Game.steamKey().
+  Lows map[string]historicalLow
+
+  // The workers look up and record concurrently.
+  m sync.Mutex
+}
+
+var history = priceHistory{Lows: map[string]historicalLow{}}
+
+// A missing file is an empty history.
+func (h *priceHistory) load(fileName string) error {
+  data, err := os.ReadFile(fileName)
+  if errors.Is(err, fs.ErrNotExist) {
+    return nil
+  }
+  if err != nil {
+    return err
+  }
+
+  h.m.Lock()
+  defer h.m.Unlock()
+  return json.Unmarshal(data, h)
+}
+
+func (h *priceHistory) save(fileName string) error {
+  h.m.Lock()
+  defer h.m.Unlock()
+  data, err := json.MarshalIndent(h, "", "  ")
+  if err != nil {
+    return err
+  }
+  return os.WriteFile(fileName, data, 0644)
+}
+
+// Returns the lowest recorded price for |game|, -1 if there is none.
+func (h *priceHistory) lowest(game Game) float32 {
+  h.m.Lock()
+  defer h.m.Unlock()
+  low, found := h.Lows[game.steamKey()]
+  if !found {
+    return -1
+  }
+  return low.Price
+}
+
+func (h *priceHistory) record(game Game) {
+  if game.backend == "" || game.minPrice < 0 {
+    return
+  }
+
+  h.m.Lock()
+  defer h.m.Unlock()
+  key := game.steamKey()
+  low, found := h.Lows[key]
+  if found && low.Price <= game.minPrice {
+    return
+  }
+  h.Lows[key] = historicalLow{game.minPrice, game.backend, time.Now().Format("2006-01-02")}
+}
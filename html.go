@@ -0,0 +1,169 @@
+package main
+
+import (
+  "fmt"
+  "html/template"
+  "io"
+  "time"
+)
+
+// Self-contained HTML report for -format html: no external assets,
+// the style and the column sorting script are inlined.
+
+// A game is "close" when its price is within this ratio of the target price.
+const cCloseToTargetRatio = 1.25
+
+type htmlReportGame struct {
+  jsonReportGame
+  // Offer per store, nil if the store has no offer.
+  Offers []*jsonReportOffer
+  // CSS class for the price-vs-target coloring: "matching", "close", "over" or "none".
+  Class string
+}
+
+type htmlReportSection struct {
+  Title string
+  Games []htmlReportGame
+}
+
+type htmlReport struct {
+  GeneratedAt string
+  Stores []string
+  Sections []htmlReportSection
+  Errors []jsonReportError
+  HasOwners bool
+}
+
+func priceClass(game Game, matching bool) string {
+  if matching {
+    return "matching"
+  }
+  if game.backend == "" || game.minPrice < 0 {
+    return "none"
+  }
+  targetPrice, hasPrice := game.criteria.target.highestPrice()
+  if hasPrice && game.minPrice <= targetPrice * cCloseToTargetRatio {
+    return "close"
+  }
+  return "over"
+}
+
+func newHTMLReportSection(title string, games []Game, matching bool, report *htmlReport) htmlReportSection {
+  section := htmlReportSection{title, []htmlReportGame{}}
+  for _, game := range games {
+    reportGame := htmlReportGame{jsonReportGame: newJSONReportGame(game), Class: priceClass(game, matching)}
+    for _, store := range allStores {
+      var storeOffer *jsonReportOffer
+      for idx := range reportGame.Stores {
+        if reportGame.Stores[idx].Store == store {
+          storeOffer = &reportGame.Stores[idx]
+        }
+      }
+      reportGame.Offers = append(reportGame.Offers, storeOffer)
+    }
+    report.HasOwners = report.HasOwners || game.criteria.owner != ""
+    section.Games = append(section.Games, reportGame)
+  }
+  return section
+}
+
+func writeHTMLReport(writer io.Writer, output *Output, now time.Time) error {
+  report := htmlReport{GeneratedAt: now.Format("2006-01-02 15:04:05 MST")}
+  for _, store := range allStores {
+    report.Stores = append(report.Stores, storeTitles[store])
+  }
+  report.Sections = []htmlReportSection{
+    newHTMLReportSection("Unreleased games", output.unreleasedGames, false, &report),
+    newHTMLReportSection("Games under target", output.matchingGames, true, &report),
+    newHTMLReportSection("Games over target", output.otherGames, false, &report),
+  }
+  report.Errors = newJSONReportErrors(output.errors)
+  return htmlReportTemplate.Execute(writer, report)
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+  "money": func(price float32) string { return fmt.Sprintf("$%.2f", price) },
+  "deref": func(price *float32) float32 { return *price },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Game deals - {{.GeneratedAt}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; }
+th { background: #eee; cursor: pointer; user-select: none; }
+th.asc::after { content: " \25B2"; }
+th.desc::after { content: " \25BC"; }
+td.price { text-align: right; white-space: nowrap; }
+td.best { font-weight: bold; }
+tr.matching td.status { background: #c8f7c5; }
+tr.close td.status { background: #fbe3b0; }
+tr.over td.status { background: #f7c5c5; }
+tr.none td.status { background: #e0e0e0; }
+.muted { color: #888; }
+</style>
+</head>
+<body>
+<h1>Game deals</h1>
+<p class="muted">Generated on {{.GeneratedAt}}</p>
+{{- range .Sections}}
+<h2>{{.Title}} ({{len .Games}})</h2>
+{{- if .Games}}
+<table class="sortable">
+<thead><tr><th>Game</th>{{if $.HasOwners}}<th>Owner</th>{{end}}<th>Price</th>{{range $.Stores}}<th>{{.}}</th>{{end}}<th>Target</th></tr></thead>
+<tbody>
+{{- range .Games}}
+<tr class="{{.Class}}">
+<td data-sort="{{.Name}}"><a href="{{.URL}}">{{.Name}}</a>{{if .Group}} <span class="muted">({{.Group}})</span>{{end}}</td>
+{{- if $.HasOwners}}<td>{{.Owner}}</td>{{end}}
+{{- if .Price}}<td class="price status" data-sort="{{.Price}}">{{money (deref .Price)}}</td>{{else}}<td class="price status" data-sort="">-</td>{{end}}
+{{- $backend := .Backend}}
+{{- range .Offers}}{{if .}}<td class="price{{if eq .Store $backend}} best{{end}}" data-sort="{{.Price}}"><a href="{{.URL}}">{{money .Price}}</a></td>{{else}}<td class="price muted" data-sort="">-</td>{{end}}{{end}}
+<td>{{if .MatchedCondition}}{{.MatchedCondition}}{{else}}{{.Target}}{{end}}</td>
+</tr>
+{{- end}}
+</tbody>
+</table>
+{{- end}}
+{{- end}}
+{{- if .Errors}}
+<h2>Errors ({{len .Errors}})</h2>
+<ul>
+{{- range .Errors}}
+<li>{{.Name}}{{if .Owner}} ({{.Owner}}){{end}}: {{.Error}}</li>
+{{- end}}
+</ul>
+{{- end}}
+<script>
+document.querySelectorAll("table.sortable th").forEach(function(th) {
+  th.addEventListener("click", function() {
+    var table = th.closest("table");
+    var tbody = table.tBodies[0];
+    var column = Array.prototype.indexOf.call(th.parentNode.children, th);
+    var ascending = !th.classList.contains("asc");
+    table.querySelectorAll("th").forEach(function(other) { other.classList.remove("asc", "desc"); });
+    th.classList.add(ascending ? "asc" : "desc");
+    var key = function(row) {
+      var cell = row.children[column];
+      var value = cell.hasAttribute("data-sort") ? cell.getAttribute("data-sort") : cell.textContent;
+      // Only the price cells are numbers: names like "1917" or "7 Days to Die" are compared as text.
+      if (!cell.classList.contains("price") || value === "") { return value.toLowerCase(); }
+      return parseFloat(value);
+    };
+    var rows = Array.prototype.slice.call(tbody.rows);
+    rows.sort(function(a, b) {
+      var ka = key(a), kb = key(b);
+      // Missing prices always come last.
+      if (ka === "" || kb === "") { return ka === kb ? 0 : (ka === "" ? 1 : -1); }
+      var order = ka < kb ? -1 : (ka > kb ? 1 : 0);
+      return ascending ? order : -order;
+    });
+    rows.forEach(function(row) { tbody.appendChild(row); });
+  });
+});
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,58 @@
+package main
+
+import (
+  "bytes"
+  "strings"
+  "testing"
+  "time"
+)
+
+func TestWriteHTMLReport(t *testing.T) {
+  output := newTestReportOutput()
+  output.otherGames[0].name = "Bar <script>"
+
+  var buf bytes.Buffer
+  err := writeHTMLReport(&buf, output, time.Date(2020, 5, 6, 10, 0, 0, 0, time.UTC))
+  if err != nil {
+    t.Fatalf("Unexpected error %+v", err)
+  }
+  page := buf.String()
+
+  expectedParts := []string{
+    "Generated on 2020-05-06 10:00:00 UTC",
+    "<h2>Games under target (1)</h2>",
+    `<tr class="matching">`,
+    `<td class="price best" data-sort="4.99"><a href="https://www.fanatical.com/en/game/foobar">$4.99</a></td>`,
+    `<tr class="none">`,
+    "Bar &lt;script&gt;",
+    "<th>Owner</th>",
+    "<li>Missing: No matches</li>",
+    `if (!cell.classList.contains("price")`,
+  }
+  for _, part := range expectedParts {
+    if !strings.Contains(page, part) {
+      t.Errorf("Expected %q in the page:\n%s", part, page)
+    }
+  }
+  // Self-contained.
+  for _, external := range []string{"<link", "<script src", "<img"} {
+    if strings.Contains(page, external) {
+      t.Errorf("Unexpected external asset %q in the page", external)
+    }
+  }
+}
+
+func TestPriceClass(t *testing.T) {
+  game := pricedGame(11, -1)
+  game.criteria.target = priceTarget{targetCondition{belowPrice, 10}}
+  if class := priceClass(game, false); class != "close" {
+    t.Errorf("Expected close but got %s", class)
+  }
+  game.minPrice = 20
+  if class := priceClass(game, false); class != "over" {
+    t.Errorf("Expected over but got %s", class)
+  }
+  if class := priceClass(game, true); class != "matching" {
+    t.Errorf("Expected matching but got %s", class)
+  }
+}
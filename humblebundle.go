@@ -50,6 +50,16 @@ func hasSteamDelivery(hit hbSearchHit) bool {
 }
 
 func FillHumbleBundleInfo(game *Game) error {
+  if game.criteria.pinned.hbPath != "" {
+    pinned := Game{hb: HumbleBundleInfo{-1, game.criteria.pinned.hbPath}}
+    err, price := fetchProductPagePrice(pinned.humbleBundleURL())
+    if err != nil {
+      return err
+    }
+    game.hb = HumbleBundleInfo{price, pinned.hb.path}
+    return nil
+  }
+
   searchURL := fmt.Sprintf(cHBSearchURLMissingKey, cHBApiKey)
   if debugFlag {
     fmt.Printf("HumbleBundle search URL: \"%s\"\n", searchURL)
@@ -56,6 +56,15 @@ type loadedSearchResponse struct {
 }
 
 func FillLoadedInfo(game *Game) error {
+  if game.criteria.pinned.loadedURL != "" {
+    err, price := fetchProductPagePrice(game.criteria.pinned.loadedURL)
+    if err != nil {
+      return err
+    }
+    game.loaded = LoadedInfo{price, game.criteria.pinned.loadedURL}
+    return nil
+  }
+
   // We send a JSON payload as application/x-www-form-urlencoded to the search endpoint.
   buf := fmt.Sprintf("{\"requests\":[{\"indexName\":\"magento2_default_products\",\"params\":\"hitsPerPage=5&query=%s\"}]}", game.name)
   reader := strings.NewReader(buf)
@@ -1,16 +1,16 @@
 package main
 
 import (
-  "encoding/csv"
   "errors"
   "fmt"
   "flag"
   "io"
   "os"
   "sort"
-  "strconv"
   "strings"
   "sync"
+  "text/template"
+  "time"
 )
 
 // TODO: All those common structs need to go to a shared file.
@@ -21,6 +21,10 @@ type SteamInfo struct {
 
   // Game price may be -1 if none was found (unreleased games).
   price float32
+
+  // Price before discount, -1 if unknown.
+  // Only fetched when a target needs it.
+  basePrice float32
 }
 
 type FanaticalInfo struct {
@@ -69,10 +73,21 @@ type Game struct {
   gmg GreenManGamingInfo
   hb HumbleBundleInfo
   loaded LoadedInfo
+
+  // The watchlist entry the game was fetched for.
+  criteria gameCriteria
+
+  // Description of the target condition that matched, empty if none did.
+  matchedCondition string
+}
+
+func newGame() Game {
+  return Game{"", -1, "", SteamInfo{0, 0, -1, -1}, FanaticalInfo{-1, ""}, GreenManGamingInfo{-1, ""}, HumbleBundleInfo{-1, ""}, LoadedInfo{-1, ""}, gameCriteria{}, ""}
 }
 
 func (g Game) url() string {
-  if g.backend == "steam" {
+  // No allowed store has the game, point to Steam for reference.
+  if g.backend == "" || g.backend == "steam" {
     return g.steamURL()
   } else if g.backend == "fanatical" {
     return g.fanaticalURL()
@@ -88,6 +103,33 @@ func (g Game) url() string {
   }
 }
 
+type storeOffer struct {
+  backend string
+  price float32
+  url string
+}
+
+// Returns the offers of the allowed stores for the game, in order of preference (see fillMinPrice).
+func (g Game) offers() []storeOffer {
+  offers := []storeOffer{}
+  if g.steam.price >= 0 && g.criteria.allowsStore("steam") {
+    offers = append(offers, storeOffer{"steam", g.steam.price, g.steamURL()})
+  }
+  if g.fanatical.price > 0 && g.fanatical.slug != "" && g.criteria.allowsStore("fanatical") {
+    offers = append(offers, storeOffer{"fanatical", g.fanatical.price, g.fanaticalURL()})
+  }
+  if g.hb.price > 0 && g.hb.path != "" && g.criteria.allowsStore("humblebundle") {
+    offers = append(offers, storeOffer{"humblebundle", g.hb.price, g.humbleBundleURL()})
+  }
+  if g.gmg.price > 0 && g.gmg.path != "" && g.criteria.allowsStore("gmg") {
+    offers = append(offers, storeOffer{"gmg", g.gmg.price, g.greenManGamingURL()})
+  }
+  if g.loaded.price > 0 && g.loaded.url != "" && g.criteria.allowsStore("loaded") {
+    offers = append(offers, storeOffer{"loaded", g.loaded.price, g.loaded.url})
+  }
+  return offers
+}
+
 func (g Game) steamURL() string {
   if g.steam.bundleId != 0 && g.steam.id != 0 {
     panic(fmt.Sprintf("Game is both a regular game and a bundle: %+v", g))
@@ -100,6 +142,14 @@ func (g Game) steamURL() string {
   return fmt.Sprintf("https://store.steampowered.com/app/%d", g.steam.id)
 }
 
+// Identifies the game across runs, using the same syntax as the watchlist Steam references.
+func (g Game) steamKey() string {
+  if g.steam.bundleId != 0 {
+    return fmt.Sprintf("bundle/%d", g.steam.bundleId)
+  }
+  return fmt.Sprintf("app/%d", g.steam.id)
+}
+
 func (g Game) fanaticalURL() string {
   if g.fanatical.slug == "" {
     panic(fmt.Sprintf("Game doesn't have a fanatical slug: %+v", g))
@@ -125,23 +175,29 @@ func (g Game) humbleBundleURL() string {
   return fmt.Sprintf("https://www.humblebundle.com/store%s", g.hb.path)
 }
 
-type gameCriteria struct {
-  name string
-  targetPrice float32
-}
+// Returned when no Steam game matches the name, which is an input error rather than a fetch failure.
+var errNoSteamGame = errors.New("No steam game (did you mistype the name?)")
 
 func fetchAndFillGame(criteria gameCriteria) (error, *Game) {
   if debugFlag {
     fmt.Println("Fetching", criteria.name)
   }
 
-  err, game := SearchGameOnSteam(criteria.name)
-  if err != nil {
-    return err, nil
+  var game *Game
+  for _, name := range criteria.searchNames(criteria.name) {
+    var err error
+    err, game = SearchGameOnSteam(name, criteria.pinned)
+    if err != nil {
+      return err, nil
+    }
+    if game != nil {
+      break
+    }
   }
   if game == nil {
-    return errors.New("No steam game"), nil
+    return errNoSteamGame, nil
   }
+  game.criteria = criteria
 
   if game.steam.price == -1 {
     if debugFlag {
@@ -150,24 +206,42 @@ func fetchAndFillGame(criteria gameCriteria) (error, *Game) {
     return nil, game
   }
 
-  err = FillFanaticalInfo(game)
-  if err != nil {
-    return err, nil
+  // The search results only have the final price.
+  // The table report shows the discounts so it always needs the base price.
+  if (criteria.target.needsBasePrice() || formatFlag == cFormatTable) && game.steam.basePrice == -1 && game.steam.id != 0 {
+    err, details := fetchSteamApp(game.steam.id)
+    if err != nil {
+      return err, nil
+    }
+    game.steam.basePrice = details.steam.basePrice
   }
 
-  err = FillHumbleBundleInfo(game)
-  if err != nil {
-    return err, nil
+  if criteria.allowsStore("fanatical") {
+    err := fillStoreInfo(game, FillFanaticalInfo, func(g *Game) bool { return g.fanatical.slug != "" })
+    if err != nil {
+      return err, nil
+    }
   }
 
-  err = FillGreenManGamingInfo(game)
-  if err != nil {
-    return err, nil
+  if criteria.allowsStore("humblebundle") {
+    err := fillStoreInfo(game, FillHumbleBundleInfo, func(g *Game) bool { return g.hb.path != "" })
+    if err != nil {
+      return err, nil
+    }
   }
 
-  err = FillLoadedInfo(game)
-  if err != nil {
-    return err, nil
+  if criteria.allowsStore("gmg") {
+    err := fillStoreInfo(game, FillGreenManGamingInfo, func(g *Game) bool { return g.gmg.path != "" })
+    if err != nil {
+      return err, nil
+    }
+  }
+
+  if criteria.allowsStore("loaded") {
+    err := fillStoreInfo(game, FillLoadedInfo, func(g *Game) bool { return g.loaded.url != "" })
+    if err != nil {
+      return err, nil
+    }
   }
 
   if debugFlag {
@@ -176,56 +250,165 @@ func fetchAndFillGame(criteria gameCriteria) (error, *Game) {
   return nil, game
 }
 
-func gameWorker(c chan gameCriteria, output *Output) {
-  defer output.wg.Done()
-  for criteria := range(c) {
-    err, game := fetchAndFillGame(criteria)
-    if err != nil {
-      fmt.Fprintf(os.Stderr, "Error fetching game \"%s\" (err = %+v)\n", criteria.name, err)
-      continue
+// Calls |fill| with each of the search names (see gameCriteria.searchNames) until |found| reports a match.
+func fillStoreInfo(game *Game, fill func(*Game) error, found func(*Game) bool) error {
+  // The backends search for |game.name| so we swap it temporarily.
+  steamName := game.name
+  defer func() { game.name = steamName }()
+  for _, name := range game.criteria.searchNames(steamName) {
+    if debugFlag && name != steamName {
+      fmt.Printf("Searching \"%s\" as \"%s\"\n", steamName, name)
     }
+    game.name = name
+    err := fill(game)
+    if err != nil || found(game) {
+      return err
+    }
+  }
+  return nil
+}
 
-    if game == nil {
-      fmt.Fprintf(os.Stderr, "No matches for \"%s\"\n", criteria.name)
-      continue
+// Expands a series/developer/publisher entry into one entry per Steam game.
+func expandSteamQuery(criteria gameCriteria) (error, []gameCriteria) {
+  err, games := SearchQueryOnSteam(criteria.query)
+  if err != nil {
+    return err, nil
+  }
+
+  expand := func(criteria gameCriteria, game Game) gameCriteria {
+    expandedCriteria := gameCriteria{name: game.name, target: criteria.target, group: criteria.name, stores: criteria.stores, tags: criteria.tags, tag: criteria.tag, notes: criteria.notes, priority: criteria.priority, owner: criteria.owner}
+    expandedCriteria.pinned.steamId = game.steam.id
+    return expandedCriteria
+  }
+
+  expanded := []gameCriteria{}
+  for _, game := range games {
+    expandedCriteria := expand(criteria, game)
+    for _, shared := range criteria.sharedWith {
+      expandedCriteria.sharedWith = append(expandedCriteria.sharedWith, expand(shared, game))
     }
+    expanded = append(expanded, expandedCriteria)
+  }
+  return nil, expanded
+}
 
-    fillMinPrice(game)
-    splitGameOnCriteria(*game, criteria.targetPrice, output)
+// The game is fetched once and checked against the target of each owner.
+func processGame(criteria gameCriteria, output *Output) {
+  err, game := fetchAndFillGame(criteria.fetchCriteria())
+  if errors.Is(err, errNoSteamGame) {
+    fmt.Fprintf(os.Stderr, "Warning: no Steam game for \"%s\" (did you mistype the name?)\n", criteria.name)
+    output.addNotFound(criteria, fmt.Sprintf("%v", err))
+    return
+  }
+  if err != nil {
+    fmt.Fprintf(os.Stderr, "Error fetching game \"%s\" (err = %+v)\n", criteria.name, err)
+    output.addError(criteria, fmt.Sprintf("%v", err))
+    return
+  }
 
-    if debugFlag {
-      fmt.Printf("Done for \"%s\", final game: %+v\n", criteria.name, *game)
+  if game == nil {
+    fmt.Fprintf(os.Stderr, "Warning: no matches for \"%s\"\n", criteria.name)
+    output.addNotFound(criteria, "No matches")
+    return
+  }
+
+  // The history is checked before recording this run's price.
+  historicalLow := history.lowest(*game)
+  for _, ownerCriteria := range criteria.allOwners() {
+    ownerGame := *game
+    ownerGame.criteria = ownerCriteria
+    ownerGame.criteria.sharedWith = nil
+    fillMinPrice(&ownerGame)
+    if ownerCriteria.target.needsBasePrice() && game.steam.basePrice <= 0 && game.steam.price > 0 {
+      fmt.Fprintf(os.Stderr, "Ignoring the discount target of \"%s\"%s: Steam has no base price for it (bundles never have one)\n", game.name, ownerCriteria.ownerSuffix())
     }
+    ownerGame.matchedCondition = ownerCriteria.target.check(ownerGame, historicalLow)
+    splitGameOnCriteria(ownerGame, output)
+  }
+  fillMinPrice(game)
+  history.record(*game)
+
+  if debugFlag {
+    fmt.Printf("Done for \"%s\", final game: %+v\n", criteria.name, *game)
   }
 }
 
-func fillMinPrice(game *Game) {
-  // Preference is steam, fanatical, HumbleBundle (hb), GreenManGaming (gmg), loaded.
-  game.minPrice = game.steam.price
-  game.backend = "steam"
+// Games waiting for a worker. The workers push back the games of the
+// series/developer/publisher entries so they are fetched in parallel.
+type workQueue struct {
+  c chan gameCriteria
+  // Games pushed but not processed yet.
+  pending sync.WaitGroup
+}
 
-  if game.fanatical.price > 0 && game.fanatical.price < game.minPrice {
-    game.minPrice = game.fanatical.price
-    game.backend = "fanatical"
-  } 
+func newWorkQueue() *workQueue {
+  return &workQueue{c: make(chan gameCriteria, parallelism)}
+}
+
+func (q *workQueue) push(criteria gameCriteria) {
+  q.pending.Add(1)
+  q.c <- criteria
+}
+
+// Pushes from another goroutine: a worker blocking on a full queue would deadlock the pool.
+func (q *workQueue) pushAsync(criteria []gameCriteria) {
+  q.pending.Add(len(criteria))
+  go func() {
+    for _, criterium := range criteria {
+      q.c <- criterium
+    }
+  }()
+}
+
+// Closes the queue once all the games (including the pushed back ones) are processed.
+func (q *workQueue) close() {
+  q.pending.Wait()
+  close(q.c)
+}
 
-  if game.hb.price > 0 && game.hb.price < game.minPrice {
-    game.minPrice = game.hb.price
-    game.backend = "humblebundle"
+func gameWorker(q *workQueue, output *Output) {
+  defer output.wg.Done()
+  for criteria := range(q.c) {
+    processQueuedGame(criteria, q, output)
+    q.pending.Done()
   }
+}
 
-  if game.gmg.price > 0 && game.gmg.price < game.minPrice {
-    game.minPrice = game.gmg.price
-    game.backend = "gmg"
+// Series/developer/publisher entries are expanded and their games pushed back to the queue.
+func processQueuedGame(criteria gameCriteria, q *workQueue, output *Output) {
+  if criteria.query.kind == "" {
+    processGame(criteria, output)
+    return
   }
 
-  if game.loaded.price > 0 && game.loaded.price < game.minPrice {
-    game.minPrice = game.loaded.price
-    game.backend = "loaded"
+  err, expanded := expandSteamQuery(criteria)
+  if err != nil {
+    fmt.Fprintf(os.Stderr, "Error expanding \"%s\" (err = %+v)\n", criteria.name, err)
+    output.addError(criteria, fmt.Sprintf("%v", err))
+    return
+  }
+  if len(expanded) == 0 {
+    fmt.Fprintf(os.Stderr, "Warning: no matches for \"%s\"\n", criteria.name)
+    output.addNotFound(criteria, "No matches")
+    return
   }
+  q.pushAsync(expanded)
 }
 
-func splitGameOnCriteria(game Game, targetPrice float32, output *Output) {
+func fillMinPrice(game *Game) {
+  // Preference is steam, fanatical, HumbleBundle (hb), GreenManGaming (gmg), loaded (see Game.offers).
+  // If none of the allowed stores has the game, backend is left empty.
+  game.minPrice = -1
+  game.backend = ""
+  for _, offer := range game.offers() {
+    if game.backend == "" || offer.price < game.minPrice {
+      game.minPrice = offer.price
+      game.backend = offer.backend
+    }
+  }
+}
+
+func splitGameOnCriteria(game Game, output *Output) {
   output.m.Lock()
   defer output.m.Unlock()
 
@@ -234,17 +417,24 @@ func splitGameOnCriteria(game Game, targetPrice float32, output *Output) {
     return
   }
 
-  // Simple price point right now.
-  if game.minPrice < targetPrice {
+  if game.backend == "" {
+    if debugFlag {
+      fmt.Fprintf(os.Stdout, "Game \"%s\" has no offer in the allowed stores (%v)\n", game.name, game.criteria.stores)
+    }
+    output.otherGames = append(output.otherGames, game)
+    return
+  }
+
+  if game.matchedCondition != "" {
     if debugFlag {
-      fmt.Fprintf(os.Stdout, "Game \"%s\" with price = %v (backend = \"%s\") matched targetPrice = %v\n", game.name, game.minPrice, game.backend, targetPrice)
+      fmt.Fprintf(os.Stdout, "Game \"%s\" with price = %v (backend = \"%s\") matched target = %v (%s)\n", game.name, game.minPrice, game.backend, game.criteria.target, game.matchedCondition)
     }
     output.matchingGames = append(output.matchingGames, game)
     return
   }
 
   if debugFlag {
-    fmt.Fprintf(os.Stdout, "Game \"%s\" with price = %v (backend = \"%s\") was over targetPrice = %v\n", game.name, game.minPrice, game.backend, targetPrice)
+    fmt.Fprintf(os.Stdout, "Game \"%s\" with price = %v (backend = \"%s\") was over target = %v\n", game.name, game.minPrice, game.backend, game.criteria.target)
   }
   output.otherGames = append(output.otherGames, game)
 }
@@ -268,6 +458,10 @@ type Output struct {
   unreleasedGames []Game
   matchingGames []Game
   otherGames []Game
+  // Entries that couldn't be fetched.
+  errors []gameError
+  // Stores that couldn't be set up, whose offers are missing from every game.
+  backendErrors []string
   m sync.Mutex
 
   wg sync.WaitGroup
@@ -291,151 +485,496 @@ func (a ByPriceThenName) Less(i, j int) bool {
   return a[i].name < a[j].name
 }
 
-func newOutput() Output {
-  return Output{[]Game{}, []Game{}, []Game{}, sync.Mutex{}, sync.WaitGroup{}}
+// ByGroup implements sort.Interface for []game.
+// Games without group come first. Use with sort.Stable to keep the price order within a group.
+type ByGroup []Game
+func (a ByGroup) Len() int { return len(a) }
+func (a ByGroup) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a ByGroup) Less(i, j int) bool { return a[i].criteria.group < a[j].criteria.group }
+
+// ByTag implements sort.Interface for []game.
+// Games without tag come last. Use with sort.Stable to keep the group order within a tag.
+type ByTag []Game
+func (a ByTag) Len() int { return len(a) }
+func (a ByTag) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a ByTag) Less(i, j int) bool {
+  if a[i].criteria.tag == "" || a[j].criteria.tag == "" {
+    return a[j].criteria.tag == "" && a[i].criteria.tag != ""
+  }
+  return a[i].criteria.tag < a[j].criteria.tag
 }
 
-func readGamesFromFiles(fileName string) ([]gameCriteria, error) {
-  // Check that the file exist and is valid.
-  // For some reason, os.Open doesn't return an error when opening a directory.
-  stats, err := os.Stat(fileName)
-  if err != nil {
-    return []gameCriteria{}, err
+// Returns the sub-headers when |games[i]| starts a new tag or group.
+// Tags are only shown if some games have one (untagged games are sorted last).
+func groupHeaders(games []Game, i int) []string {
+  headers := []string{}
+  newTag := i == 0 || games[i - 1].criteria.tag != games[i].criteria.tag
+  if newTag && games[0].criteria.tag != "" {
+    tag := games[i].criteria.tag
+    if tag == "" {
+      tag = "untagged"
+    }
+    headers = append(headers, fmt.Sprintf("=== %s ===", tag))
   }
-  if stats.IsDir() {
-    return []gameCriteria{}, errors.New("File is a directory")
+
+  if games[i].criteria.group == "" {
+    return headers
+  }
+  if !newTag && games[i - 1].criteria.group == games[i].criteria.group {
+    return headers
   }
+  return append(headers, fmt.Sprintf("--- %s ---", games[i].criteria.group))
+}
 
-  file, err := os.Open(fileName)
-  if err != nil {
-    return []gameCriteria{}, err
+func printGroupHeader(games []Game, i int) {
+  for _, header := range groupHeaders(games, i) {
+    fmt.Fprintln(os.Stdout, header)
   }
+}
 
-    // Ensure that we close c to avoid deadlocks in case of errors.
-  defer file.Close()
+func newOutput() Output {
+  return Output{[]Game{}, []Game{}, []Game{}, []gameError{}, []string{}, sync.Mutex{}, sync.WaitGroup{}}
+}
 
-  uniqueGameNames := make(map[string] bool)
-  criteria := make([]gameCriteria, 0)
-  csvReader := csv.NewReader(file)
-  for {
-    records, err := csvReader.Read()
-    // Handle EOF as a special error.
-    if err == io.EOF {
-      break
-    }
+type gameError struct {
+  criteria gameCriteria
+  err string
+  // The game doesn't exist (mistyped name, empty series) as opposed to a failed fetch.
+  notFound bool
+}
 
-    // We want to allow an optional targetPrice.
-    // This means that we ignore ErrFieldCount errors by looking at the presence of `records`.
-    if err != nil && records == nil {
-      return []gameCriteria{}, err
-    }
-    if len(records) == 0 {
-      panic("Invalid CSV file, no record on line")
-    }
-    gameName := records[0]
+func (output *Output) recordError(criteria gameCriteria, err string, notFound bool) {
+  output.m.Lock()
+  defer output.m.Unlock()
+  for _, ownerCriteria := range criteria.allOwners() {
+    ownerCriteria.sharedWith = nil
+    output.errors = append(output.errors, gameError{ownerCriteria, err, notFound})
+  }
+}
 
-    // Check if the name is unique.
-    _, exists := uniqueGameNames[gameName]
-    if exists {
-      panic(fmt.Sprintf("Duplicated name \"%s\"", gameName))
-    }
-    uniqueGameNames[gameName] = true
+// Records the error for each owner of |criteria|.
+func (output *Output) addError(criteria gameCriteria, err string) {
+  output.recordError(criteria, err, false)
+}
 
-    // Start with our default and override it if specified.
-    targetPrice := cDefaultTargetPrice
-    if len(records) == 2 {
-      tmp, err := strconv.ParseFloat(strings.TrimSpace(records[1]), /*bitSize=*/32)
-      if err != nil {
-        return []gameCriteria{}, err
-      }
-      targetPrice = float32(tmp)
-    }
-    criteria = append(criteria, gameCriteria{gameName, targetPrice})
-  }
-  return criteria, nil
+// Records a game that doesn't exist for each owner of |criteria|, see exitCode.
+func (output *Output) addNotFound(criteria gameCriteria, err string) {
+  output.recordError(criteria, err, true)
 }
 
-func feedGamesFromFile(fileName string, c chan gameCriteria) error {
-  gameCriteria, err := readGamesFromFiles(fileName)
+// Only the entries with one of |tags| are fed, all of them if |tags| is empty.
+// Returns all the entries, including the ones that aren't fed.
+func feedGamesFromFile(fileNames []string, duplicatesRule string, tags []string, q *workQueue) ([]gameCriteria, error) {
+  gameCriteria, err := readGamesFromFiles(fileNames, duplicatesRule)
   if err != nil {
-    return err
+    return nil, err
   }
+  watched := append(gameCriteria[:0:0], gameCriteria...)
 
+  filtered := gameCriteria[:0]
   for _, gameCriterium := range gameCriteria {
-    c <- gameCriterium
+    if !gameCriterium.hasAnyTag(tags) {
+      continue
+    }
+    err = checkTargetHistory(gameCriterium.name, gameCriterium.target)
+    if err != nil {
+      return nil, err
+    }
+    gameCriterium.tag = gameCriterium.reportTag(tags)
+    filtered = append(filtered, gameCriterium)
   }
 
-  return nil
+  // Each game is fetched once for all its owners.
+  for _, gameCriterium := range groupByGame(filtered) {
+    q.push(gameCriterium)
+  }
+
+  return watched, nil
 }
 
-func feedGamesFromFlag(games string, c chan gameCriteria) {
+// Nothing is fed if one of the games is invalid.
+// Returns the fed entries.
+func feedGamesFromFlag(games string, q *workQueue) ([]gameCriteria, error) {
+  criteria := []gameCriteria{}
   tokens := strings.Split(games, ",")
   idx := 0
   for ; idx < len(tokens); idx++ {
     gameName := tokens[idx];
     // Start with our default and override it if specified.
-    targetPrice := cDefaultTargetPrice
+    target := defaultTarget
     if idx < len(tokens) - 1 {
       lookAheadToken := tokens[idx + 1]
-      tmp, err := strconv.ParseFloat(lookAheadToken, /*bitSize=*/32)
+      tmp, err := parseTarget(lookAheadToken)
       if err == nil {
-        targetPrice = float32(tmp)
-        // Skip next token as it was an optional price.
+        target = tmp
+        // Skip next token as it was an optional target.
         idx += 1
       }
     }
-    c <- gameCriteria{gameName, targetPrice}
+    err := checkTargetHistory(gameName, target)
+    if err != nil {
+      return nil, err
+    }
+    criteria = append(criteria, newGameCriteria(gameName, target))
+  }
+
+  for _, gameCriterium := range criteria {
+    q.push(gameCriterium)
   }
+  return criteria, nil
 }
 
 func main() {
+  os.Exit(run())
+}
+
+// Returns the exit code, see exitcode.go.
+func run() int {
+  // Subcommands are handled separately from the fetching flags.
+  if len(os.Args) > 1 {
+    if subcommand, found := subcommands[os.Args[1]]; found {
+      err := subcommand(os.Args[2:])
+      if err != nil {
+        fmt.Fprintf(os.Stderr, "Error running %s (err = %+v)\n", os.Args[1], err)
+        return cExitFatal
+      }
+      return cExitOK
+    }
+  }
+
   flag.BoolVar(&debugFlag, "debug", false, "Enable debug statements")
   flag.StringVar(&gamesFlag, "games", "", "Commad separated list of games to fetch")
-  flag.StringVar(&fileFlag, "file", "", "File containing a CSV list of games")
+  flag.Var(&fileFlag, "file", "File containing a CSV list of games, - for stdin (can be repeated)")
+  flag.StringVar(&duplicatesFlag, "duplicates", cDuplicatesWarn, "Rule for games in several entries: warn (keep the first one) or lowest (keep the lowest target)")
+  flag.StringVar(&targetFlag, "target", fmt.Sprintf("%v", cDefaultTargetPrice), "Default target for games without one (e.g. 10, 75% or low)")
+  flag.StringVar(&tagsFlag, "tags", "", "Comma separated list of tags: only the games with one of them are fetched")
+  flag.StringVar(&formatFlag, "format", cFormatText, "Report format: text, table, json, csv, tsv, html or markdown")
+  flag.StringVar(&templateFlag, "template", "", "text/template file used to render the report (see template.go)")
+  flag.BoolVar(&wideFlag, "wide", false, "With -format csv or tsv, one row per game with a price column per store")
+  flag.BoolVar(&collapseFlag, "collapse", false, "With -format markdown, folds the games over target in a collapsible block")
+  flag.Float64Var(&budgetFlag, "budget", 0, "Amount to spend: picks the games under target with the highest total priority that fit")
+  flag.StringVar(&historyFlag, "history", "", "File recording the lowest prices across runs (needed for \"low\" targets)")
+  flag.StringVar(&snapshotFlag, "snapshot", "", "File saving the report of each run (needed for -since-last)")
+  flag.BoolVar(&sinceLastFlag, "since-last", false, "Only report the changes since the run saved in -snapshot")
+  flag.StringVar(&feedFlag, "feed", "", "Atom feed file where the games under target are added")
+  flag.StringVar(&colorFlag, "color", cColorAuto, "Colorized report fitting the terminal width: auto (when stdout is a terminal), always or never")
+  flag.BoolVar(&hyperlinksFlag, "hyperlinks", false, "With the colorized report, links the names to the offers instead of printing the URLs (OSC 8)")
+  flag.BoolVar(&quietFlag, "quiet", false, "Only print the games under target")
   flag.Parse()
 
-  if gamesFlag == "" && fileFlag == "" || (gamesFlag != "" && fileFlag != "") {
-    fmt.Printf("Usage: main [-debug] [-file <file>] [-games game1,7,game2,game3]\n\n\nEither -file or -games must be set, but not both.\n\n<file> contains one game name per line along with a potential target price divided by ','\nExample: Foobar, 10\n")
-    return
+  if gamesFlag == "" && len(fileFlag) == 0 || (gamesFlag != "" && len(fileFlag) != 0) {
+    fmt.Printf("Usage: main [-debug] [-target <target>] [-history <file>] [-snapshot <file> [-since-last]] [-quiet] [-color auto|always|never [-hyperlinks]] [-feed <file>] [-duplicates warn|lowest] [-tags tag1,tag2] [-budget <amount>] [-format text|table|json|csv|tsv|html|markdown] [-wide] [-collapse] [-template <file>] [-file <file>]... [-games game1,7,game2,game3]\n\n\nEither -file or -games must be set, but not both.\n\n-file can be repeated to merge several watchlists and - reads the watchlist from stdin (once).\nGames present in several entries are kept once: the first entry is used with a warning (-duplicates warn)\nor the one with the lowest target price (-duplicates lowest).\n\n-tags only fetches the games of the -file watchlists with one of the tags (case insensitive). The report is grouped by tag (the first matching one).\n\n-budget picks the games under target to buy with the amount, maximizing the total priority (priority=<n>, 1 if unset).\nThe basket is printed with the text and table reports only.\n\n-format table shows the price and discount of every store, the cheapest one being marked with '*', and the saving against Steam.\n-format json writes the report as a JSON document (see report.go).\n-format csv (or tsv) writes one row per game per store, or one row per game with a column per store with -wide.\n-format html writes a self-contained HTML page with sortable columns.\n-format markdown writes a table per section with links to the stores, the games over target being folded in a <details> block with -collapse.\n-template renders the report with a text/template file (see template.go for the data and helpers).\n-color always colors the text report and fits it to the terminal width (default when stdout is a terminal, see terminal.go).\n-hyperlinks links the names to the offers instead of printing the URLs, for terminals supporting OSC 8 hyperlinks.\n\n-snapshot saves the report of each run and -since-last only reports the changes since the previous one:\ngames newly under target, prices that dropped further, games back over target and newly released games.\nThe games that couldn't be fetched or were skipped by -tags keep their previous state in the snapshot,\nthe games removed from the watchlist are dropped from it.\n\n-feed adds the games under target to an Atom feed file. A game gets a new entry when its price or store changes.\n\n-quiet only prints the games under target, one per line.\nThe exit code is 0 when games are under target, 1 when none is, 2 on invalid flags or files and 3 when some games or stores couldn't be fetched.\nGames not found on Steam are only reported as warnings (and in the errors of the reports).\n\n<file> contains one game name per line along with a potential target price divided by ','\nExample: Foobar, 10\n\nBlank lines and lines starting with '#' are ignored and names containing ',' must be quoted.\nThe first line can be a header naming the columns (name, target or an option below)\nExample: name,target,stores,notes\n\nGames can also be given as app/<appid>, bundle/<bundleid> or a Steam store URL.\n\nStore identifiers can be pinned to bypass name matching with steam=<appid>, bundle=<bundleid>, fanatical=<slug>, hb=<path>, gmg=<path> or loaded=<url>\nExample: Foobar, 10, steam=12345, fanatical=foobar\n\nAlternate names tried when the name finds nothing are given with alt=<name>\nExample: Foobar, 10, alt=Foo Bar\n\nAll the Steam games of a series, developer or publisher (up to 1000) are tracked with series:<name>, developer:<name> or publisher:<name>\nExample: series: Foobar, 10\n\nOther options are stores=<store;store>, edition=<edition>, tags=<tag;tag>, notes=<notes>, priority=<n> and owner=<user>\nExample: Foobar, 10, stores=steam;fanatical, edition=GOTY Edition\n\nIn shared watchlists, a [owner=<user>] line starts the section of a user (same as owner=<user> on the following games)\nand [owner=] ends it.\nEach game is fetched once, checked against every user's target and the report is split per user.\n\nFiles ending in .json use the structured JSON format (see watchlist.go).\n\nTargets are '|' separated conditions among a price (10), a discount off the Steam base price (75%%) or the historical low recorded in -history (low)\nExample: Foobar, 10|75%%|low\n\nSubcommands:\n  import-steam -wishlist <file> [-owned <file>] -file <file>: adds the Steam wishlist to the watchlist\n  import -file <file> [-format waitlist-csv|waitlist-json|appids] <export>...: adds the games exported from other deal trackers (waitlist exports or Steam app id lists)\n  add -file <file> [-target <target>] [-owner <user>] <game>...: adds the games after checking them on Steam\n  remove -file <file> [-owner <user>] <game>...: removes the games\n  set-target -file <file> [-owner <user>] <game> <target>: changes the target of a game\n  list -file <file>: lists the watched games\n  dedupe -file <file>: removes the duplicate games, keeping the first entry\n")
+    return cExitFatal
+  }
+
+  if gamesFlag != "" && tagsFlag != "" {
+    fmt.Fprintf(os.Stderr, "-tags can't be combined with -games (tags are set in the watchlist files)\n")
+    return cExitFatal
+  }
+
+  var err error
+  _, err = parseDuplicatesRule(duplicatesFlag)
+  if err != nil {
+    fmt.Fprintf(os.Stderr, "Invalid -duplicates=%s (err = %+v)\n", duplicatesFlag, err)
+    return cExitFatal
+  }
+
+  defaultTarget, err = parseTarget(targetFlag)
+  if err != nil {
+    fmt.Fprintf(os.Stderr, "Invalid -target=%s (err = %+v)\n", targetFlag, err)
+    return cExitFatal
+  }
+  if defaultTarget.needsHistory() && historyFlag == "" {
+    fmt.Fprintf(os.Stderr, "Invalid -target=%s (\"low\" needs -history)\n", targetFlag)
+    return cExitFatal
+  }
+
+  switch formatFlag {
+    case cFormatText, cFormatTable, cFormatJSON, cFormatCSV, cFormatTSV, cFormatHTML, cFormatMarkdown:
+    default:
+      fmt.Fprintf(os.Stderr, "Invalid -format=%s (expected text, table, json, csv, tsv, html or markdown)\n", formatFlag)
+      return cExitFatal
+  }
+
+  var reportTemplate *template.Template
+  if templateFlag != "" {
+    if formatFlag != cFormatText {
+      fmt.Fprintf(os.Stderr, "-template can't be combined with -format=%s\n", formatFlag)
+      return cExitFatal
+    }
+    reportTemplate, err = parseReportTemplate(templateFlag)
+    if err != nil {
+      fmt.Fprintf(os.Stderr, "Invalid template=%s (err = %+v)\n", templateFlag, err)
+      return cExitFatal
+    }
+  }
+
+  if sinceLastFlag {
+    if snapshotFlag == "" {
+      fmt.Fprintf(os.Stderr, "-since-last needs -snapshot\n")
+      return cExitFatal
+    }
+    if formatFlag != cFormatText || templateFlag != "" {
+      fmt.Fprintf(os.Stderr, "-since-last can't be combined with -format=%s or -template\n", formatFlag)
+      return cExitFatal
+    }
+  }
+
+  switch colorFlag {
+    case cColorAuto, cColorAlways, cColorNever:
+    default:
+      fmt.Fprintf(os.Stderr, "Invalid -color=%s (expected auto, always or never)\n", colorFlag)
+      return cExitFatal
+  }
+
+  if quietFlag && (formatFlag != cFormatText || templateFlag != "" || sinceLastFlag) {
+    fmt.Fprintf(os.Stderr, "-quiet can't be combined with -format=%s, -template or -since-last\n", formatFlag)
+    return cExitFatal
+  }
+
+  if budgetFlag < 0 {
+    fmt.Fprintf(os.Stderr, "Invalid -budget=%v (must be positive)\n", budgetFlag)
+    return cExitFatal
+  }
+  // The basket is only printed by the text and table reports.
+  if budgetFlag > 0 && ((formatFlag != cFormatText && formatFlag != cFormatTable) || templateFlag != "" || quietFlag || sinceLastFlag) {
+    fmt.Fprintf(os.Stderr, "-budget can't be combined with -format=%s, -template, -quiet or -since-last\n", formatFlag)
+    return cExitFatal
+  }
+
+  if historyFlag != "" {
+    err = history.load(historyFlag)
+    if err != nil {
+      fmt.Fprintf(os.Stderr, "Error loading history=%s (err = %+v)\n", historyFlag, err)
+      return cExitFatal
+    }
   }
 
-  InitFanatical()
+  // Loaded before fetching so that an invalid snapshot doesn't waste a run.
+  var previousSnapshot *jsonReport
+  if snapshotFlag != "" {
+    previousSnapshot, err = loadSnapshot(snapshotFlag)
+    if err != nil {
+      fmt.Fprintf(os.Stderr, "Error loading snapshot=%s (err = %+v)\n", snapshotFlag, err)
+      return cExitFatal
+    }
+  }
 
-  c := make(chan gameCriteria, parallelism)
+  q := newWorkQueue()
   output := newOutput()
+
+  err = InitFanatical()
+  if err != nil {
+    fmt.Fprintf(os.Stderr, "Error initializing Fanatical, its offers are skipped (err = %+v)\n", err)
+    output.backendErrors = append(output.backendErrors, fmt.Sprintf("fanatical: %v", err))
+  }
   output.wg.Add(parallelism)
 
   // Start the workers.
   for i := 0; i < parallelism; i++ {
-      go gameWorker(c, &output)
+      go gameWorker(q, &output)
   }
 
   // Feed the games as they are read.
-  if (fileFlag != "") {
-    err := feedGamesFromFile(fileFlag, c)
+  var watched []gameCriteria
+  if (len(fileFlag) != 0) {
+    watched, err = feedGamesFromFile(fileFlag, duplicatesFlag, parseTags(tagsFlag), q)
     if err != nil {
-      fmt.Fprintf(os.Stderr, "Error processing file=%s (err = %+v)\n", fileFlag, err)
-      return
+      fmt.Fprintf(os.Stderr, "Error processing file=%s (err = %+v)\n", fileFlag.String(), err)
+      return cExitFatal
     }
   } else {
-    feedGamesFromFlag(gamesFlag, c)
+    watched, err = feedGamesFromFlag(gamesFlag, q)
+    if err != nil {
+      fmt.Fprintf(os.Stderr, "Error processing games=%s (err = %+v)\n", gamesFlag, err)
+      return cExitFatal
+    }
   }
 
   // Make sure the channel is closed.
-  close(c)
+  q.close()
   output.wg.Wait()
 
+  // The report is still written when the files can't be saved.
+  failed := false
+  if historyFlag != "" {
+    err = history.save(historyFlag)
+    if err != nil {
+      fmt.Fprintf(os.Stderr, "Error saving history=%s (err = %+v)\n", historyFlag, err)
+      failed = true
+    }
+  }
+
+  sortOutput(&output)
+  snapshot := newJSONReport(&output, time.Now())
+  if previousSnapshot != nil {
+    snapshot = carryForward(*previousSnapshot, snapshot, newWatchedEntries(watched))
+  }
+  if sinceLastFlag {
+    printChangesSinceLast(previousSnapshot, snapshot)
+  } else {
+    err = writeReport(&output, reportTemplate)
+    if err != nil {
+      fmt.Fprintf(os.Stderr, "Error writing the report (err = %+v)\n", err)
+      failed = true
+    }
+  }
+
+  if snapshotFlag != "" {
+    err = saveSnapshot(snapshotFlag, snapshot)
+    if err != nil {
+      fmt.Fprintf(os.Stderr, "Error saving snapshot=%s (err = %+v)\n", snapshotFlag, err)
+      failed = true
+    }
+  }
+
+  if feedFlag != "" {
+    err = writeFeed(feedFlag, output.matchingGames, time.Now())
+    if err != nil {
+      fmt.Fprintf(os.Stderr, "Error writing feed=%s (err = %+v)\n", feedFlag, err)
+      failed = true
+    }
+  }
+
+  if failed {
+    return cExitFatal
+  }
+  return exitCode(&output)
+}
+
+// Without a previous snapshot, every game under target is new.
+func printChangesSinceLast(previous *jsonReport, snapshot jsonReport) {
+  if previous == nil {
+    fmt.Fprintf(os.Stdout, "No previous run in %s\n\n", snapshotFlag)
+    previous = &jsonReport{}
+  } else {
+    fmt.Fprintf(os.Stdout, "Changes since the run of %s\n\n", previous.GeneratedAt)
+  }
+  printChanges(os.Stdout, diffSnapshots(*previous, snapshot))
+}
+
+// Writes the report on stdout in the -format (or -template) format, only the games under target with -quiet.
+func writeReport(output *Output, reportTemplate *template.Template) error {
+  switch {
+    case quietFlag:
+      printMatchingGames(os.Stdout, output.matchingGames)
+      return nil
+    case reportTemplate != nil:
+      return writeTemplateReport(os.Stdout, reportTemplate, output, time.Now())
+    case formatFlag == cFormatJSON:
+      return writeJSONReport(os.Stdout, output, time.Now())
+    case formatFlag == cFormatHTML:
+      return writeHTMLReport(os.Stdout, output, time.Now())
+    case formatFlag == cFormatMarkdown:
+      return writeMarkdownReport(os.Stdout, output, collapseFlag, time.Now())
+    case formatFlag == cFormatCSV || formatFlag == cFormatTSV:
+      comma := ','
+      if formatFlag == cFormatTSV {
+        comma = '\t'
+      }
+      if wideFlag {
+        return writeWideCSVReport(os.Stdout, output, comma)
+      }
+      return writeCSVReport(os.Stdout, output, comma)
+  }
+  printTextReport(output)
+  return nil
+}
+
+// Shared watchlists get one report per owner.
+func printTextReport(output *Output) {
+  if formatFlag == cFormatText && useTerminalReport(colorFlag) {
+    terminalRenderer{os.Stdout, terminalWidth(os.Stdout), hyperlinksFlag}.printTextReport(output)
+    return
+  }
+
+  owners, ownerOutputs := splitOutputByOwner(output)
+  for _, owner := range owners {
+    if owner != "" {
+      fmt.Fprintf(os.Stdout, "##################################################\n")
+      fmt.Fprintf(os.Stdout, "# Report for %s\n", owner)
+      fmt.Fprintf(os.Stdout, "##################################################\n")
+    }
+    printReport(ownerOutputs[owner])
+    if owner != owners[len(owners) - 1] {
+      fmt.Fprintf(os.Stdout, "\n\n")
+    }
+  }
+}
+
+// Splits the games by owner. Games without owner come first.
+func splitOutputByOwner(output *Output) ([]string, map[string]*Output) {
+  owners := []string{}
+  ownerOutputs := make(map[string]*Output)
+  ownerOutput := func(game Game) *Output {
+    owner := game.criteria.owner
+    if _, found := ownerOutputs[owner]; !found {
+      newOwnerOutput := newOutput()
+      ownerOutputs[owner] = &newOwnerOutput
+      owners = append(owners, owner)
+    }
+    return ownerOutputs[owner]
+  }
+
+  for _, game := range output.unreleasedGames {
+    split := ownerOutput(game)
+    split.unreleasedGames = append(split.unreleasedGames, game)
+  }
+  for _, game := range output.matchingGames {
+    split := ownerOutput(game)
+    split.matchingGames = append(split.matchingGames, game)
+  }
+  for _, game := range output.otherGames {
+    split := ownerOutput(game)
+    split.otherGames = append(split.otherGames, game)
+  }
+  for _, gameError := range output.errors {
+    split := ownerOutput(Game{criteria: gameError.criteria})
+    split.errors = append(split.errors, gameError)
+  }
+  if len(owners) == 0 {
+    owners = append(owners, "")
+    ownerOutputs[""] = output
+  }
+  sort.Strings(owners)
+  return owners, ownerOutputs
+}
+
+func sortOutput(output *Output) {
   // Sort the output by price, then name.
   // This gives a stable sort for quickly assessing games.
   sort.Sort(ByPriceThenName(output.unreleasedGames))
   sort.Sort(ByPriceThenName(output.matchingGames))
   sort.Sort(ByPriceThenName(output.otherGames))
+  // Games expanded from the same entry are shown together.
+  sort.Stable(ByGroup(output.unreleasedGames))
+  sort.Stable(ByGroup(output.matchingGames))
+  sort.Stable(ByGroup(output.otherGames))
+  // Then by tag, see -tags.
+  sort.Stable(ByTag(output.unreleasedGames))
+  sort.Stable(ByTag(output.matchingGames))
+  sort.Stable(ByTag(output.otherGames))
+  sort.Slice(output.errors, func(i, j int) bool { return output.errors[i].criteria.name < output.errors[j].criteria.name })
+}
+
+func formatMatchingGame(game Game) string {
+  return fmt.Sprintf("%s: $%.2f - %s (%s)", game.name, game.minPrice, game.url(), game.matchedCondition)
+}
+
+// Prints one line per game for -quiet, without headers. The owner is appended for shared watchlists.
+func printMatchingGames(writer io.Writer, games []Game) {
+  for _, game := range games {
+    fmt.Fprintf(writer, "%s%s\n", formatMatchingGame(game), game.criteria.ownerSuffix())
+  }
+}
 
+func printReport(output *Output) {
   if len(output.unreleasedGames) > 0 {
     fmt.Fprintf(os.Stdout, "==================================================\n")
     fmt.Fprintf(os.Stdout, "============== Unreleased games ==================\n")
     fmt.Fprintf(os.Stdout, "==================================================\n")
-    for _, game := range output.unreleasedGames {
+    for i, game := range output.unreleasedGames {
+      printGroupHeader(output.unreleasedGames, i)
       fmt.Fprintf(os.Stdout, "%s - %s \n", game.name, game.steamURL())
     }
     fmt.Fprintf(os.Stdout, "\n\n")
@@ -445,17 +984,35 @@ func main() {
     fmt.Fprintf(os.Stdout, "==================================================\n")
     fmt.Fprintf(os.Stdout, "============== Games under target ================\n")
     fmt.Fprintf(os.Stdout, "==================================================\n")
-    for _, game := range output.matchingGames {
-      fmt.Fprintf(os.Stdout, "%s: $%.2f - %s \n", game.name, game.minPrice, game.url())
+    if formatFlag == cFormatTable {
+      printPriceTable(os.Stdout, output.matchingGames)
+    } else {
+      for i, game := range output.matchingGames {
+        printGroupHeader(output.matchingGames, i)
+        fmt.Fprintln(os.Stdout, formatMatchingGame(game))
+      }
     }
     fmt.Fprintf(os.Stdout, "\n\n")
   }
 
+  if budgetFlag > 0 {
+    printBasket(selectBasket(output.matchingGames, float32(budgetFlag)), float32(budgetFlag))
+  }
+
   fmt.Fprintf(os.Stdout, "==================================================\n")
   fmt.Fprintf(os.Stdout, "=============== Games over target ================\n")
   fmt.Fprintf(os.Stdout, "==================================================\n")
-  for _, game := range output.otherGames {
-    fmt.Fprintf(os.Stdout, "%s: $%.2f - %s\n", game.name, game.minPrice, game.url())
+  if formatFlag == cFormatTable {
+    printPriceTable(os.Stdout, output.otherGames)
+  } else {
+    for i, game := range output.otherGames {
+      printGroupHeader(output.otherGames, i)
+      if game.backend == "" {
+        fmt.Fprintf(os.Stdout, "%s: no offer in %s - %s\n", game.name, strings.Join(game.criteria.stores, ", "), game.url())
+        continue
+      }
+      fmt.Fprintf(os.Stdout, "%s: $%.2f - %s\n", game.name, game.minPrice, game.url())
+    }
   }
   fmt.Fprintf(os.Stdout, "==================================================\n")
 }
@@ -0,0 +1,56 @@
+package main
+
+import (
+  "sync"
+  "testing"
+)
+
+func TestWorkQueuePushBack(t *testing.T) {
+  q := newWorkQueue()
+  processed := make(map[string]bool)
+  var m sync.Mutex
+  var wg sync.WaitGroup
+  wg.Add(parallelism)
+  for i := 0; i < parallelism; i++ {
+    go func() {
+      defer wg.Done()
+      for criteria := range(q.c) {
+        // Like a series entry, each "query" expands to more games than the queue holds.
+        if criteria.query.kind != "" {
+          expanded := []gameCriteria{}
+          for j := 0; j < 3 * parallelism; j++ {
+            expanded = append(expanded, gameCriteria{name: criteria.name + string(rune('a' + j))})
+          }
+          q.pushAsync(expanded)
+        }
+        m.Lock()
+        processed[criteria.name] = true
+        m.Unlock()
+        q.pending.Done()
+      }
+    }()
+  }
+
+  for i := 0; i < 2 * parallelism; i++ {
+    q.push(gameCriteria{name: string(rune('A' + i)), query: steamQuery{kind: "publisher"}})
+  }
+  q.close()
+  wg.Wait()
+
+  expected := 2 * parallelism * (1 + 3 * parallelism)
+  if len(processed) != expected {
+    t.Errorf("Expected %d processed entries but got %d", expected, len(processed))
+  }
+}
+
+func TestFileListStdinOnce(t *testing.T) {
+  var files fileList
+  for _, fileName := range []string{"-", "games.csv"} {
+    if err := files.Set(fileName); err != nil {
+      t.Fatalf("Unexpected error for %s (err = %+v)", fileName, err)
+    }
+  }
+  if err := files.Set("-"); err == nil {
+    t.Errorf("Expected an error for a second - but got %+v", files)
+  }
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+  "fmt"
+  "io"
+  "strings"
+  "time"
+)
+
+// Markdown report for -format markdown, to paste in chats and issue trackers.
+// Each section is a table with the best store and the other stores linked to their offers.
+// -collapse folds the games over target in a <details> block.
+
+var markdownEscaper = strings.NewReplacer("|", "\\|", "[", "\\[", "]", "\\]", "*", "\\*", "_", "\\_", "`", "\\`", "<", "&lt;", ">", "&gt;")
+
+func markdownLink(text string, url string) string {
+  return fmt.Sprintf("[%s](%s)", markdownEscaper.Replace(text), url)
+}
+
+func markdownOfferLink(offer storeOffer) string {
+  return fmt.Sprintf("%s $%.2f", markdownLink(storeTitles[offer.backend], offer.url), offer.price)
+}
+
+// Returns the cells of |game|: name, price, best store, other stores and target, then the owner with |hasOwners|.
+func markdownRow(game Game, hasOwners bool) []string {
+  name := markdownLink(game.name, game.steamURL())
+  if game.criteria.group != "" {
+    name += fmt.Sprintf(" (%s)", markdownEscaper.Replace(game.criteria.group))
+  }
+  target := game.criteria.target.String()
+  if game.matchedCondition != "" {
+    target = game.matchedCondition
+  }
+
+  row := []string{name, "-", "-", "", markdownEscaper.Replace(target)}
+  switch {
+    case game.steam.price == -1:
+      row[2] = "unreleased"
+    case game.backend == "":
+      row[2] = "no offer in " + strings.Join(game.criteria.stores, ", ")
+    default:
+      row[1] = fmt.Sprintf("$%.2f", game.minPrice)
+      others := []string{}
+      for _, offer := range game.offers() {
+        if offer.backend == game.backend {
+          row[2] = markdownLink(storeTitles[offer.backend], offer.url)
+          continue
+        }
+        others = append(others, markdownOfferLink(offer))
+      }
+      row[3] = strings.Join(others, ", ")
+  }
+  if hasOwners {
+    row = append(row, markdownEscaper.Replace(game.criteria.owner))
+  }
+  return row
+}
+
+func writeMarkdownRow(writer io.Writer, cells []string) {
+  fmt.Fprintf(writer, "| %s |\n", strings.Join(cells, " | "))
+}
+
+func writeMarkdownTable(writer io.Writer, games []Game, hasOwners bool) {
+  header := []string{"Game", "Price", "Store", "Other stores", "Target"}
+  if hasOwners {
+    header = append(header, "Owner")
+  }
+  writeMarkdownRow(writer, header)
+  separators := []string{}
+  for range header {
+    separators = append(separators, "---")
+  }
+  separators[1] = "--:"
+  writeMarkdownRow(writer, separators)
+  for _, game := range games {
+    writeMarkdownRow(writer, markdownRow(game, hasOwners))
+  }
+}
+
+func writeMarkdownReport(writer io.Writer, output *Output, collapse bool, now time.Time) error {
+  hasOwners := false
+  for _, reportGame := range reportGames(output) {
+    hasOwners = hasOwners || reportGame.game.criteria.owner != ""
+  }
+
+  fmt.Fprintf(writer, "# Game deals\n\n")
+  fmt.Fprintf(writer, "_Generated on %s_\n", now.Format("2006-01-02 15:04:05 MST"))
+
+  if len(output.unreleasedGames) > 0 {
+    fmt.Fprintf(writer, "\n## Unreleased games (%d)\n\n", len(output.unreleasedGames))
+    writeMarkdownTable(writer, output.unreleasedGames, hasOwners)
+  }
+
+  fmt.Fprintf(writer, "\n## Games under target (%d)\n\n", len(output.matchingGames))
+  if len(output.matchingGames) > 0 {
+    writeMarkdownTable(writer, output.matchingGames, hasOwners)
+  } else {
+    fmt.Fprintf(writer, "No game under target.\n")
+  }
+
+  if len(output.otherGames) > 0 {
+    if collapse {
+      // GitHub and most trackers need the blank lines to render the table inside the block.
+      fmt.Fprintf(writer, "\n<details>\n<summary>Games over target (%d)</summary>\n\n", len(output.otherGames))
+      writeMarkdownTable(writer, output.otherGames, hasOwners)
+      fmt.Fprintf(writer, "\n</details>\n")
+    } else {
+      fmt.Fprintf(writer, "\n## Games over target (%d)\n\n", len(output.otherGames))
+      writeMarkdownTable(writer, output.otherGames, hasOwners)
+    }
+  }
+
+  if len(output.errors) > 0 {
+    fmt.Fprintf(writer, "\n## Errors (%d)\n\n", len(output.errors))
+    for _, gameError := range output.errors {
+      fmt.Fprintf(writer, "- %s%s: %s\n", markdownEscaper.Replace(gameError.criteria.name), markdownEscaper.Replace(gameError.criteria.ownerSuffix()), markdownEscaper.Replace(gameError.err))
+    }
+  }
+  return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+  "bytes"
+  "testing"
+  "time"
+)
+
+func TestWriteMarkdownReport(t *testing.T) {
+  tt := []struct {
+    name string
+    collapse bool
+    expected string
+  } {
+    {"Sections", false, `# Game deals
+
+_Generated on 2020-05-06 10:00:00 UTC_
+
+## Games under target (1)
+
+| Game | Price | Store | Other stores | Target | Owner |
+| --- | --: | --- | --- | --- | --- |
+| [Foobar](https://store.steampowered.com/app/10) | $4.99 | [Fanatical](https://www.fanatical.com/en/game/foobar) | [Steam](https://store.steampowered.com/app/10) $9.99 | under $5.00 | alice |
+
+## Games over target (1)
+
+| Game | Price | Store | Other stores | Target | Owner |
+| --- | --: | --- | --- | --- | --- |
+| [Bar\|foo](https://store.steampowered.com/app/20) | - | no offer in gmg |  | $7.00 |  |
+
+## Errors (1)
+
+- Missing: No matches
+`},
+    {"Collapsed", true, `# Game deals
+
+_Generated on 2020-05-06 10:00:00 UTC_
+
+## Games under target (1)
+
+| Game | Price | Store | Other stores | Target | Owner |
+| --- | --: | --- | --- | --- | --- |
+| [Foobar](https://store.steampowered.com/app/10) | $4.99 | [Fanatical](https://www.fanatical.com/en/game/foobar) | [Steam](https://store.steampowered.com/app/10) $9.99 | under $5.00 | alice |
+
+<details>
+<summary>Games over target (1)</summary>
+
+| Game | Price | Store | Other stores | Target | Owner |
+| --- | --: | --- | --- | --- | --- |
+| [Bar\|foo](https://store.steampowered.com/app/20) | - | no offer in gmg |  | $7.00 |  |
+
+</details>
+
+## Errors (1)
+
+- Missing: No matches
+`},
+  }
+
+  for _, tc := range(tt) {
+    t.Run(tc.name, func(t *testing.T) {
+      output := newTestReportOutput()
+      output.otherGames[0].name = "Bar|foo"
+      // Released but not in the allowed stores.
+      output.otherGames[0].steam.price = 19.99
+
+      var buf bytes.Buffer
+      err := writeMarkdownReport(&buf, output, tc.collapse, time.Date(2020, 5, 6, 10, 0, 0, 0, time.UTC))
+      if err != nil {
+        t.Fatalf("Unexpected error %+v", err)
+      }
+      if buf.String() != tc.expected {
+        t.Errorf("Expected:\n%s\nbut got:\n%s", tc.expected, buf.String())
+      }
+    })
+  }
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+  "encoding/json"
+  "errors"
+  "fmt"
+  "io"
+  "net/http"
+  "regexp"
+  "strconv"
+  "strings"
+)
+
+// Price lookup on the product page of pinned store identifiers (fanatical=, hb=, gmg=, loaded=):
+// the pinned page is fetched directly instead of searching the store by name.
+//
+// The price is read from the schema.org Product data (<script type="application/ld+json">)
+// or the Open Graph product:price:amount meta tag, which the stores publish for search engines.
+// The pages are localized by geo-IP: only USD prices are used, like the searches and the targets.
+
+var ldJSONRegexp = regexp.MustCompile(`(?is)<script[^>]+type=["']application/ld\+json["'][^>]*>(.*?)</script>`)
+var metaRegexp = regexp.MustCompile(`(?is)<meta\s[^>]*>`)
+var metaAttrRegexp = regexp.MustCompile(`(?is)\b(property|content)=["']([^"']*)["']`)
+
+// Returns an error for non-2xx responses so a missing page isn't taken as "no offer".
+func checkHTTPStatus(resp *http.Response) error {
+  if resp.StatusCode < 200 || resp.StatusCode > 299 {
+    return fmt.Errorf("Unexpected HTTP status %s for %s", resp.Status, resp.Request.URL)
+  }
+  return nil
+}
+
+// Returns the USD price of the offers in a JSON-LD value, -1 if there is none.
+func findLDJSONPrice(value interface{}) float32 {
+  switch typed := value.(type) {
+    case []interface{}:
+      for _, item := range typed {
+        if price := findLDJSONPrice(item); price >= 0 {
+          return price
+        }
+      }
+    case map[string]interface{}:
+      if offers, found := typed["offers"]; found {
+        if price := findOfferPrice(offers); price >= 0 {
+          return price
+        }
+      }
+      if graph, found := typed["@graph"]; found {
+        return findLDJSONPrice(graph)
+      }
+  }
+  return -1
+}
+
+func findOfferPrice(offers interface{}) float32 {
+  candidates := []map[string]interface{}{}
+  switch typed := offers.(type) {
+    case map[string]interface{}:
+      candidates = append(candidates, typed)
+    case []interface{}:
+      for _, item := range typed {
+        if offer, ok := item.(map[string]interface{}); ok {
+          candidates = append(candidates, offer)
+        }
+      }
+  }
+
+  for _, offer := range candidates {
+    if currency, _ := offer["priceCurrency"].(string); currency != "USD" {
+      continue
+    }
+    // AggregateOffer only has a low price.
+    value, found := offer["price"]
+    if !found {
+      value = offer["lowPrice"]
+    }
+    var parsed float64
+    var err error
+    switch typedValue := value.(type) {
+      case float64:
+        parsed = typedValue
+      case string:
+        parsed, err = strconv.ParseFloat(typedValue, 32)
+      default:
+        continue
+    }
+    if err != nil {
+      continue
+    }
+    return float32(parsed)
+  }
+  return -1
+}
+
+// Returns the content of the <meta property="..."> tags, keyed by property.
+func parseMetaProperties(page []byte) map[string]string {
+  properties := make(map[string]string)
+  for _, tag := range metaRegexp.FindAll(page, -1) {
+    attrs := make(map[string]string)
+    for _, attr := range metaAttrRegexp.FindAllSubmatch(tag, -1) {
+      attrs[strings.ToLower(string(attr[1]))] = string(attr[2])
+    }
+    if _, found := properties[attrs["property"]]; !found && attrs["property"] != "" {
+      properties[attrs["property"]] = attrs["content"]
+    }
+  }
+  return properties
+}
+
+func parseProductPagePrice(page []byte) (error, float32) {
+  for _, match := range ldJSONRegexp.FindAllSubmatch(page, -1) {
+    var value interface{}
+    if json.Unmarshal(match[1], &value) != nil {
+      continue
+    }
+    if price := findLDJSONPrice(value); price >= 0 {
+      return nil, price
+    }
+  }
+
+  properties := parseMetaProperties(page)
+  if properties["product:price:currency"] == "USD" {
+    price, err := strconv.ParseFloat(properties["product:price:amount"], 32)
+    if err == nil {
+      return nil, float32(price)
+    }
+  }
+  return errors.New("No USD price on the product page"), -1
+}
+
+func fetchProductPagePrice(pageURL string) (error, float32) {
+  if debugFlag {
+    fmt.Printf("Fetching pinned product page \"%s\"\n", pageURL)
+  }
+  resp, err := http.Get(pageURL)
+  if err != nil {
+    return err, -1
+  }
+  defer resp.Body.Close()
+  err = checkHTTPStatus(resp)
+  if err != nil {
+    return err, -1
+  }
+
+  page, err := io.ReadAll(resp.Body)
+  if err != nil {
+    return err, -1
+  }
+  err, price := parseProductPagePrice(page)
+  if err != nil {
+    return fmt.Errorf("%v (%s)", err, pageURL), -1
+  }
+  return nil, price
+}
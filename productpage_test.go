@@ -0,0 +1,37 @@
+package main
+
+import (
+  "testing"
+)
+
+func TestParseProductPagePrice(t *testing.T) {
+  tt := []struct {
+    name string
+    page string
+    expected float32
+    expectedErr bool
+  } {
+    {"JSON-LD offer", `<html><script type="application/ld+json">{"@type":"Product","name":"Foobar","offers":{"@type":"Offer","price":"4.99","priceCurrency":"USD"}}</script></html>`, 4.99, false},
+    {"JSON-LD USD offer among others", `<script type="application/ld+json">{"@type":"Product","offers":[{"price":3.5,"priceCurrency":"EUR"},{"price":4.99,"priceCurrency":"USD"}]}</script>`, 4.99, false},
+    {"Rejects JSON-LD in EUR only", `<script type="application/ld+json">{"@type":"Product","offers":{"price":3.5,"priceCurrency":"EUR"}}</script>`, -1, true},
+    {"Rejects JSON-LD without currency", `<script type="application/ld+json">{"@type":"Product","offers":{"price":3.5}}</script>`, -1, true},
+    {"JSON-LD graph", `<script type="application/ld+json">{"@graph":[{"@type":"WebPage"},{"@type":"Product","offers":{"@type":"AggregateOffer","lowPrice":"9.99","priceCurrency":"USD"}}]}</script>`, 9.99, false},
+    {"Skips invalid JSON-LD", `<script type="application/ld+json">{</script><meta property="product:price:amount" content="12.49"/><meta property="product:price:currency" content="USD"/>`, 12.49, false},
+    {"Open Graph meta", `<meta property="product:price:amount" content="12.49"/><meta property="product:price:currency" content="USD"/>`, 12.49, false},
+    {"Open Graph meta with content first", `<meta content="7.00" property="product:price:amount"><meta content="USD" property="product:price:currency">`, 7, false},
+    {"Rejects Open Graph meta in GBP", `<meta property="product:price:amount" content="7.00"/><meta property="product:price:currency" content="GBP"/>`, -1, true},
+    {"No price", `<html><title>Foobar</title></html>`, -1, true},
+  }
+
+  for _, tc := range(tt) {
+    t.Run(tc.name, func(t *testing.T) {
+      err, price := parseProductPagePrice([]byte(tc.page))
+      if (err != nil) != tc.expectedErr {
+        t.Fatalf("Unexpected error %+v", err)
+      }
+      if price != tc.expected {
+        t.Errorf("Expected %v but got %v", tc.expected, price)
+      }
+    })
+  }
+}
@@ -0,0 +1,277 @@
+package main
+
+import (
+  "encoding/csv"
+  "encoding/json"
+  "fmt"
+  "io"
+  "strconv"
+  "time"
+)
+
+// Report formats, see -format.
+const (
+  cFormatText = "text"
+  // The text report with a per-store price table, see printPriceTable.
+  cFormatTable = "table"
+  cFormatJSON = "json"
+  cFormatCSV = "csv"
+  cFormatTSV = "tsv"
+  cFormatHTML = "html"
+  cFormatMarkdown = "markdown"
+)
+
+// Version of the JSON report.
+// It is bumped on incompatible changes (removed or renamed fields), not on additions.
+const cReportVersion = 1
+
+// JSON report format:
+// {
+//   "version": 1,
+//   "generatedAt": "2006-01-02T15:04:05Z",
+//   "unreleased": [<game>...],
+//   "matching": [<game>...],
+//   "other": [<game>...],
+//   "errors": [{"name": "Foobar", "error": "No matches", "notFound": true}]
+// }
+// with <game>:
+// {
+//   "name": "Foobar",
+//   "entry": "foobar",
+//   "steamId": 12345,
+//   "backend": "fanatical",
+//   "price": 4.99,
+//   "url": "https://www.fanatical.com/en/game/foobar",
+//   "target": "$7.00",
+//   "targetConditions": [{"kind": "price", "value": 7}],
+//   "matchedCondition": "under $7.00",
+//   "stores": [{"store": "steam", "price": 9.99, "url": "..."}, {"store": "fanatical", "price": 4.99, "url": "..."}]
+// }
+// "entry" is the name of the watchlist entry (the series entry for the games of a series).
+// "backend" is empty and "price" null when no allowed store has the game.
+// The games are in the order of the text report.
+type jsonReport struct {
+  Version int `json:"version"`
+  GeneratedAt string `json:"generatedAt"`
+  Unreleased []jsonReportGame `json:"unreleased"`
+  Matching []jsonReportGame `json:"matching"`
+  Other []jsonReportGame `json:"other"`
+  Errors []jsonReportError `json:"errors"`
+}
+
+type jsonReportOffer struct {
+  Store string `json:"store"`
+  Price float32 `json:"price"`
+  URL string `json:"url"`
+}
+
+type jsonReportCondition struct {
+  // "price", "percentOff" or "historicalLow".
+  Kind string `json:"kind"`
+  Value float32 `json:"value,omitempty"`
+}
+
+type jsonReportGame struct {
+  Name string `json:"name"`
+  Entry string `json:"entry,omitempty"`
+  SteamId int `json:"steamId,omitempty"`
+  SteamBundleId int `json:"steamBundleId,omitempty"`
+  Backend string `json:"backend"`
+  Price *float32 `json:"price"`
+  URL string `json:"url"`
+  Target string `json:"target"`
+  TargetConditions []jsonReportCondition `json:"targetConditions"`
+  MatchedCondition string `json:"matchedCondition,omitempty"`
+  Stores []jsonReportOffer `json:"stores"`
+
+  Group string `json:"group,omitempty"`
+  Tags []string `json:"tags,omitempty"`
+  Notes string `json:"notes,omitempty"`
+  Priority int `json:"priority,omitempty"`
+  Owner string `json:"owner,omitempty"`
+}
+
+type jsonReportError struct {
+  Name string `json:"name"`
+  Group string `json:"group,omitempty"`
+  Owner string `json:"owner,omitempty"`
+  Error string `json:"error"`
+  // The game wasn't found (mistyped name) rather than couldn't be fetched.
+  NotFound bool `json:"notFound,omitempty"`
+}
+
+func newJSONReportConditions(target priceTarget) []jsonReportCondition {
+  conditions := []jsonReportCondition{}
+  for _, condition := range target {
+    switch condition.kind {
+      case belowPrice:
+        conditions = append(conditions, jsonReportCondition{"price", condition.value})
+      case percentOffBase:
+        conditions = append(conditions, jsonReportCondition{"percentOff", condition.value})
+      case atHistoricalLow:
+        conditions = append(conditions, jsonReportCondition{"historicalLow", 0})
+    }
+  }
+  return conditions
+}
+
+func newJSONReportGame(game Game) jsonReportGame {
+  reportGame := jsonReportGame{
+    Name: game.name,
+    Entry: game.criteria.entryName(),
+    SteamId: game.steam.id,
+    SteamBundleId: game.steam.bundleId,
+    Backend: game.backend,
+    URL: game.url(),
+    Target: game.criteria.target.String(),
+    TargetConditions: newJSONReportConditions(game.criteria.target),
+    MatchedCondition: game.matchedCondition,
+    Stores: []jsonReportOffer{},
+    Group: game.criteria.group,
+    Tags: game.criteria.tags,
+    Notes: game.criteria.notes,
+    Priority: game.criteria.priority,
+    Owner: game.criteria.owner,
+  }
+  if game.backend != "" && game.minPrice >= 0 {
+    price := game.minPrice
+    reportGame.Price = &price
+  }
+  for _, offer := range game.offers() {
+    reportGame.Stores = append(reportGame.Stores, jsonReportOffer{offer.backend, offer.price, offer.url})
+  }
+  return reportGame
+}
+
+func newJSONReportGames(games []Game) []jsonReportGame {
+  reportGames := []jsonReportGame{}
+  for _, game := range games {
+    reportGames = append(reportGames, newJSONReportGame(game))
+  }
+  return reportGames
+}
+
+func newJSONReportErrors(gameErrors []gameError) []jsonReportError {
+  reportErrors := []jsonReportError{}
+  for _, gameError := range gameErrors {
+    reportErrors = append(reportErrors, jsonReportError{gameError.criteria.name, gameError.criteria.group, gameError.criteria.owner, gameError.err, gameError.notFound})
+  }
+  return reportErrors
+}
+
+func newJSONReport(output *Output, now time.Time) jsonReport {
+  return jsonReport{
+    Version: cReportVersion,
+    GeneratedAt: now.UTC().Format(time.RFC3339),
+    Unreleased: newJSONReportGames(output.unreleasedGames),
+    Matching: newJSONReportGames(output.matchingGames),
+    Other: newJSONReportGames(output.otherGames),
+    Errors: newJSONReportErrors(output.errors),
+  }
+}
+
+func writeJSONReport(writer io.Writer, output *Output, now time.Time) error {
+  encoder := json.NewEncoder(writer)
+  encoder.SetIndent("", "  ")
+  return encoder.Encode(newJSONReport(output, now))
+}
+
+type reportGame struct {
+  game Game
+  // Bucket of the game: "unreleased", "matching" or "other".
+  status string
+}
+
+// Returns the games of all the buckets, in the order of the text report.
+func reportGames(output *Output) []reportGame {
+  games := []reportGame{}
+  buckets := []struct {
+    status string
+    games []Game
+  } {
+    {"unreleased", output.unreleasedGames},
+    {"matching", output.matchingGames},
+    {"other", output.otherGames},
+  }
+  for _, bucket := range buckets {
+    for _, game := range bucket.games {
+      games = append(games, reportGame{game, bucket.status})
+    }
+  }
+  return games
+}
+
+func formatCSVPrice(price float32) string {
+  return strconv.FormatFloat(float64(price), 'f', 2, /*bitSize=*/32)
+}
+
+// Returns the highest price condition of |target|, "" without price condition.
+func formatTargetPrice(target priceTarget) string {
+  price, hasPrice := target.highestPrice()
+  if !hasPrice {
+    return ""
+  }
+  return formatCSVPrice(price)
+}
+
+func formatSteamId(game Game) string {
+  if game.steam.bundleId != 0 {
+    return fmt.Sprintf("bundle/%d", game.steam.bundleId)
+  }
+  return strconv.Itoa(game.steam.id)
+}
+
+// Writes one row per game per store:
+// name, steam_id, store, price, url, target, target_price, matched, status, owner.
+// target_price is the (highest) price condition of the target, empty without one.
+// Games without offer get a single row with an empty store and price.
+// |comma| is ',' for CSV and '\t' for TSV.
+func writeCSVReport(writer io.Writer, output *Output, comma rune) error {
+  csvWriter := csv.NewWriter(writer)
+  csvWriter.Comma = comma
+  csvWriter.Write([]string{"name", "steam_id", "store", "price", "url", "target", "target_price", "matched", "status", "owner"})
+  for _, reportGame := range reportGames(output) {
+    game := reportGame.game
+    matched := strconv.FormatBool(game.matchedCondition != "")
+    targetPrice := formatTargetPrice(game.criteria.target)
+    offers := game.offers()
+    if len(offers) == 0 {
+      csvWriter.Write([]string{game.name, formatSteamId(game), "", "", game.url(), game.criteria.target.String(), targetPrice, matched, reportGame.status, game.criteria.owner})
+      continue
+    }
+    for _, offer := range offers {
+      csvWriter.Write([]string{game.name, formatSteamId(game), offer.backend, formatCSVPrice(offer.price), offer.url, game.criteria.target.String(), targetPrice, matched, reportGame.status, game.criteria.owner})
+    }
+  }
+  csvWriter.Flush()
+  return csvWriter.Error()
+}
+
+// Writes one row per game with a price column per store (empty if the store has no offer):
+// name, steam_id, <store>..., best_store, best_price, url, target, target_price, matched, status, owner.
+func writeWideCSVReport(writer io.Writer, output *Output, comma rune) error {
+  csvWriter := csv.NewWriter(writer)
+  csvWriter.Comma = comma
+  header := []string{"name", "steam_id"}
+  header = append(header, allStores...)
+  csvWriter.Write(append(header, "best_store", "best_price", "url", "target", "target_price", "matched", "status", "owner"))
+  for _, reportGame := range reportGames(output) {
+    game := reportGame.game
+    prices := make(map[string]string)
+    for _, offer := range game.offers() {
+      prices[offer.backend] = formatCSVPrice(offer.price)
+    }
+
+    row := []string{game.name, formatSteamId(game)}
+    for _, store := range allStores {
+      row = append(row, prices[store])
+    }
+    bestPrice := ""
+    if game.backend != "" && game.minPrice >= 0 {
+      bestPrice = formatCSVPrice(game.minPrice)
+    }
+    csvWriter.Write(append(row, game.backend, bestPrice, game.url(), game.criteria.target.String(), formatTargetPrice(game.criteria.target), strconv.FormatBool(game.matchedCondition != ""), reportGame.status, game.criteria.owner))
+  }
+  csvWriter.Flush()
+  return csvWriter.Error()
+}
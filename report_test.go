@@ -0,0 +1,127 @@
+package main
+
+import (
+  "bytes"
+  "encoding/json"
+  "reflect"
+  "strings"
+  "testing"
+  "time"
+)
+
+func newTestReportOutput() *Output {
+  matching := pricedGame(9.99, 20)
+  matching.name = "Foobar"
+  matching.steam.id = 10
+  matching.fanatical = FanaticalInfo{4.99, "foobar"}
+  matching.gmg = GreenManGamingInfo{3.99, "/games/foobar"}
+  matching.criteria = gameCriteria{name: "Foobar", target: priceTarget{targetCondition{belowPrice, 5}, targetCondition{atHistoricalLow, 0}}, stores: []string{"steam", "fanatical"}, owner: "alice"}
+  fillMinPrice(&matching)
+  matching.matchedCondition = "under $5.00"
+
+  noOffer := newGame()
+  noOffer.name = "Barfoo"
+  noOffer.steam.id = 20
+  noOffer.criteria = gameCriteria{name: "Barfoo", target: defaultTarget, stores: []string{"gmg"}}
+  fillMinPrice(&noOffer)
+
+  output := newOutput()
+  output.matchingGames = []Game{matching}
+  output.otherGames = []Game{noOffer}
+  output.errors = []gameError{gameError{gameCriteria{name: "Missing"}, "No matches", true}}
+  return &output
+}
+
+func TestWriteJSONReport(t *testing.T) {
+  output := newTestReportOutput()
+  var buf bytes.Buffer
+  err := writeJSONReport(&buf, output, time.Date(2020, 5, 6, 10, 0, 0, 0, time.UTC))
+  if err != nil {
+    t.Fatalf("Unexpected error %+v", err)
+  }
+
+  var report jsonReport
+  err = json.Unmarshal(buf.Bytes(), &report)
+  if err != nil {
+    t.Fatalf("Invalid report %s (err = %+v)", buf.String(), err)
+  }
+  if report.Version != cReportVersion || report.GeneratedAt != "2020-05-06T10:00:00Z" || len(report.Unreleased) != 0 {
+    t.Errorf("Unexpected report %s", buf.String())
+  }
+
+  price := float32(4.99)
+  expected := jsonReportGame{
+    Name: "Foobar",
+    Entry: "Foobar",
+    SteamId: 10,
+    Backend: "fanatical",
+    Price: &price,
+    URL: "https://www.fanatical.com/en/game/foobar",
+    Target: "$5.00 or historical low",
+    TargetConditions: []jsonReportCondition{jsonReportCondition{"price", 5}, jsonReportCondition{"historicalLow", 0}},
+    MatchedCondition: "under $5.00",
+    Stores: []jsonReportOffer{
+      jsonReportOffer{"steam", 9.99, "https://store.steampowered.com/app/10"},
+      jsonReportOffer{"fanatical", 4.99, "https://www.fanatical.com/en/game/foobar"},
+    },
+    Owner: "alice",
+  }
+  if len(report.Matching) != 1 || !reflect.DeepEqual(report.Matching[0], expected) {
+    t.Errorf("Expected %+v but got %+v", expected, report.Matching)
+  }
+
+  if len(report.Other) != 1 || report.Other[0].Price != nil || report.Other[0].Backend != "" || len(report.Other[0].Stores) != 0 {
+    t.Errorf("Expected no offer for %+v", report.Other)
+  }
+  if !reflect.DeepEqual(report.Errors, []jsonReportError{jsonReportError{Name: "Missing", Error: "No matches", NotFound: true}}) {
+    t.Errorf("Unexpected errors %+v", report.Errors)
+  }
+}
+
+func TestWriteCSVReport(t *testing.T) {
+  output := newTestReportOutput()
+  var buf bytes.Buffer
+  err := writeCSVReport(&buf, output, ',')
+  if err != nil {
+    t.Fatalf("Unexpected error %+v", err)
+  }
+  expected := strings.Join([]string{
+    "name,steam_id,store,price,url,target,target_price,matched,status,owner",
+    "Foobar,10,steam,9.99,https://store.steampowered.com/app/10,$5.00 or historical low,5.00,true,matching,alice",
+    "Foobar,10,fanatical,4.99,https://www.fanatical.com/en/game/foobar,$5.00 or historical low,5.00,true,matching,alice",
+    "Barfoo,20,,,https://store.steampowered.com/app/20,$7.00,7.00,false,other,",
+    "",
+  }, "\n")
+  if buf.String() != expected {
+    t.Errorf("Expected\n%s\nbut got\n%s", expected, buf.String())
+  }
+
+  // No price condition.
+  output.otherGames[0].criteria.target = priceTarget{targetCondition{percentOffBase, 75}}
+  buf.Reset()
+  err = writeCSVReport(&buf, output, ',')
+  if err != nil {
+    t.Fatalf("Unexpected error %+v", err)
+  }
+  if row := "Barfoo,20,,,https://store.steampowered.com/app/20,75% off,,false,other,\n"; !strings.HasSuffix(buf.String(), row) {
+    t.Errorf("Expected an empty target_price in %s", buf.String())
+  }
+}
+
+func TestWriteWideCSVReport(t *testing.T) {
+  output := newTestReportOutput()
+  var buf bytes.Buffer
+  err := writeWideCSVReport(&buf, output, '\t')
+  if err != nil {
+    t.Fatalf("Unexpected error %+v", err)
+  }
+  expected := strings.Join([]string{
+    "name\tsteam_id\tsteam\tfanatical\thumblebundle\tgmg\tloaded\tbest_store\tbest_price\turl\ttarget\ttarget_price\tmatched\tstatus\towner",
+    "Foobar\t10\t9.99\t4.99\t\t\t\tfanatical\t4.99\thttps://www.fanatical.com/en/game/foobar\t$5.00 or historical low\t5.00\ttrue\tmatching\talice",
+    "Barfoo\t20\t\t\t\t\t\t\t\thttps://store.steampowered.com/app/20\t$7.00\t7.00\tfalse\tother\t",
+    "",
+  }, "\n")
+  if buf.String() != expected {
+    t.Errorf("Expected\n%s\nbut got\n%s", expected, buf.String())
+  }
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+  "encoding/json"
+  "errors"
+  "fmt"
+  "io"
+  "io/fs"
+  "os"
+)
+
+// Snapshots of the runs (-snapshot) and the changes since the last one (-since-last).
+// A snapshot is the JSON report of the run (see jsonReport).
+
+// Identifies a game across runs.
+type snapshotKey struct {
+  steamId int
+  steamBundleId int
+  owner string
+  // Only used for games without Steam id.
+  name string
+}
+
+func newSnapshotKey(game jsonReportGame) snapshotKey {
+  key := snapshotKey{steamId: game.SteamId, steamBundleId: game.SteamBundleId, owner: game.Owner}
+  if game.SteamId == 0 && game.SteamBundleId == 0 {
+    key.name = normalizeName(game.Name)
+  }
+  return key
+}
+
+// Returns the previous snapshot, nil if there is none.
+func loadSnapshot(fileName string) (*jsonReport, error) {
+  data, err := os.ReadFile(fileName)
+  if errors.Is(err, fs.ErrNotExist) {
+    return nil, nil
+  }
+  if err != nil {
+    return nil, err
+  }
+
+  var snapshot jsonReport
+  err = json.Unmarshal(data, &snapshot)
+  if err != nil {
+    return nil, err
+  }
+  if snapshot.Version != cReportVersion {
+    return nil, fmt.Errorf("Unsupported snapshot version %d (expected %d)", snapshot.Version, cReportVersion)
+  }
+  return &snapshot, nil
+}
+
+func saveSnapshot(fileName string, snapshot jsonReport) error {
+  data, err := json.MarshalIndent(snapshot, "", "  ")
+  if err != nil {
+    return err
+  }
+  return os.WriteFile(fileName, data, 0644)
+}
+
+// Identifies a watchlist entry of an owner.
+type watchedEntry struct {
+  owner string
+  name string
+}
+
+// Returns the entries of the watchlists of the run, including the ones skipped by -tags.
+func newWatchedEntries(criteria []gameCriteria) map[watchedEntry]bool {
+  watched := make(map[watchedEntry]bool)
+  for _, criterium := range criteria {
+    watched[watchedEntry{criterium.owner, normalizeName(criterium.name)}] = true
+  }
+  return watched
+}
+
+// Snapshots written before "entry" was reported only have the name.
+func newWatchedEntry(game jsonReportGame) watchedEntry {
+  name := game.Entry
+  if name == "" {
+    name = game.Name
+  }
+  return watchedEntry{game.Owner, normalizeName(name)}
+}
+
+func appendMissingGames(games []jsonReportGame, previousGames []jsonReportGame, seen map[snapshotKey]bool, watched map[watchedEntry]bool) []jsonReportGame {
+  for _, game := range previousGames {
+    if !seen[newSnapshotKey(game)] && watched[newWatchedEntry(game)] {
+      games = append(games, game)
+    }
+  }
+  return games
+}
+
+// Returns |current| with the games of |previous| missing from it that are still |watched|.
+// The games that couldn't be fetched or were skipped by -tags keep their last known state,
+// so they aren't reported as new by the next run. The games removed from the watchlists
+// are dropped, so they are reported again if they are added back.
+func carryForward(previous jsonReport, current jsonReport, watched map[watchedEntry]bool) jsonReport {
+  seen := make(map[snapshotKey]bool)
+  for _, games := range [][]jsonReportGame{current.Unreleased, current.Matching, current.Other} {
+    for _, game := range games {
+      seen[newSnapshotKey(game)] = true
+    }
+  }
+  current.Unreleased = appendMissingGames(current.Unreleased, previous.Unreleased, seen, watched)
+  current.Matching = appendMissingGames(current.Matching, previous.Matching, seen, watched)
+  current.Other = appendMissingGames(current.Other, previous.Other, seen, watched)
+  return current
+}
+
+type changedGame struct {
+  game jsonReportGame
+  // Price in the previous snapshot, nil if the game had none (or wasn't there).
+  previousPrice *float32
+}
+
+type runChanges struct {
+  // Under target now but not in the previous run.
+  newlyMatching []changedGame
+  // Under target in both runs with a lower price now.
+  droppedFurther []changedGame
+  // Under target in the previous run but not anymore.
+  backOverTarget []changedGame
+  // Unreleased in the previous run.
+  released []changedGame
+}
+
+func diffSnapshots(previous jsonReport, current jsonReport) runChanges {
+  previousGames := make(map[snapshotKey]jsonReportGame)
+  previousBuckets := make(map[snapshotKey]string)
+  buckets := []struct {
+    name string
+    games []jsonReportGame
+  } {
+    {"unreleased", previous.Unreleased},
+    {"matching", previous.Matching},
+    {"other", previous.Other},
+  }
+  for _, bucket := range buckets {
+    for _, game := range bucket.games {
+      key := newSnapshotKey(game)
+      previousGames[key] = game
+      previousBuckets[key] = bucket.name
+    }
+  }
+
+  changes := runChanges{}
+  for _, bucket := range []struct {
+    matching bool
+    games []jsonReportGame
+  } {{true, current.Matching}, {false, current.Other}} {
+    for _, game := range bucket.games {
+      key := newSnapshotKey(game)
+      previousGame := previousGames[key]
+      changed := changedGame{game, previousGame.Price}
+      switch previousBuckets[key] {
+        case "unreleased":
+          changes.released = append(changes.released, changed)
+        case "matching":
+          if !bucket.matching {
+            changes.backOverTarget = append(changes.backOverTarget, changed)
+          } else if game.Price != nil && previousGame.Price != nil && *game.Price < *previousGame.Price {
+            changes.droppedFurther = append(changes.droppedFurther, changed)
+          }
+        default:
+          if bucket.matching {
+            changes.newlyMatching = append(changes.newlyMatching, changed)
+          }
+      }
+    }
+  }
+  return changes
+}
+
+func formatChangedPrice(changed changedGame) string {
+  price := "no offer"
+  if changed.game.Price != nil {
+    price = fmt.Sprintf("$%.2f", *changed.game.Price)
+  }
+  if changed.previousPrice != nil {
+    price += fmt.Sprintf(" (was $%.2f)", *changed.previousPrice)
+  }
+  return price
+}
+
+// Prints the changes in the text report style.
+func printChanges(writer io.Writer, changes runChanges) {
+  sections := []struct {
+    title string
+    games []changedGame
+  } {
+    {"============ Newly under target ==================", changes.newlyMatching},
+    {"============ Price dropped further ===============", changes.droppedFurther},
+    {"============ Back over target ====================", changes.backOverTarget},
+    {"============ Newly released ======================", changes.released},
+  }
+
+  empty := true
+  for _, section := range sections {
+    if len(section.games) == 0 {
+      continue
+    }
+    empty = false
+    fmt.Fprintf(writer, "==================================================\n")
+    fmt.Fprintf(writer, "%s\n", section.title)
+    fmt.Fprintf(writer, "==================================================\n")
+    for _, changed := range section.games {
+      fmt.Fprintf(writer, "%s%s: %s - %s", changed.game.Name, gameCriteria{owner: changed.game.Owner}.ownerSuffix(), formatChangedPrice(changed), changed.game.URL)
+      if changed.game.MatchedCondition != "" {
+        fmt.Fprintf(writer, " (%s)", changed.game.MatchedCondition)
+      }
+      fmt.Fprintf(writer, "\n")
+    }
+    fmt.Fprintf(writer, "\n\n")
+  }
+  if empty {
+    fmt.Fprintf(writer, "Nothing new\n")
+  }
+}
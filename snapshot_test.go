@@ -0,0 +1,121 @@
+package main
+
+import (
+  "path/filepath"
+  "reflect"
+  "testing"
+)
+
+func TestDiffSnapshots(t *testing.T) {
+  game := func(name string, steamId int, price float32) jsonReportGame {
+    return jsonReportGame{Name: name, SteamId: steamId, Price: &price}
+  }
+  unpriced := func(name string, steamId int) jsonReportGame {
+    return jsonReportGame{Name: name, SteamId: steamId}
+  }
+  previous := jsonReport{
+    Unreleased: []jsonReportGame{unpriced("Released", 1), unpriced("Still unreleased", 2)},
+    Matching: []jsonReportGame{game("Dropped", 3, 10), game("Same price", 4, 10), game("Back over", 5, 10), game("Raised", 6, 10)},
+    Other: []jsonReportGame{game("Crossed", 7, 20), game("Still over", 8, 20)},
+  }
+  current := jsonReport{
+    Unreleased: []jsonReportGame{unpriced("Still unreleased", 2)},
+    Matching: []jsonReportGame{game("Released", 1, 5), game("Dropped", 3, 8), game("Same price", 4, 10), game("Raised", 6, 12), game("Crossed", 7, 9), game("Added", 9, 1)},
+    Other: []jsonReportGame{game("Back over", 5, 15), game("Still over", 8, 18)},
+  }
+
+  names := func(games []changedGame) []string {
+    names := []string{}
+    for _, changed := range games {
+      names = append(names, changed.game.Name)
+    }
+    return names
+  }
+  changes := diffSnapshots(previous, current)
+  tt := []struct {
+    name string
+    games []changedGame
+    expected []string
+  } {
+    {"Newly matching", changes.newlyMatching, []string{"Crossed", "Added"}},
+    {"Dropped further", changes.droppedFurther, []string{"Dropped"}},
+    {"Back over target", changes.backOverTarget, []string{"Back over"}},
+    {"Released", changes.released, []string{"Released"}},
+  }
+
+  for _, tc := range(tt) {
+    t.Run(tc.name, func(t *testing.T) {
+      if got := names(tc.games); !reflect.DeepEqual(got, tc.expected) {
+        t.Errorf("Expected %+v but got %+v", tc.expected, got)
+      }
+    })
+  }
+
+  if len(changes.droppedFurther) != 1 || *changes.droppedFurther[0].previousPrice != 10 {
+    t.Errorf("Expected the previous price of Dropped (10) but got %+v", changes.droppedFurther)
+  }
+}
+
+func TestDiffSnapshotsPerOwner(t *testing.T) {
+  price := float32(5)
+  previous := jsonReport{Matching: []jsonReportGame{{Name: "Foobar", SteamId: 1, Price: &price, Owner: "alice"}}}
+  current := jsonReport{Matching: []jsonReportGame{
+    {Name: "Foobar", SteamId: 1, Price: &price, Owner: "alice"},
+    {Name: "Foobar", SteamId: 1, Price: &price, Owner: "bob"},
+  }}
+  changes := diffSnapshots(previous, current)
+  if len(changes.newlyMatching) != 1 || changes.newlyMatching[0].game.Owner != "bob" {
+    t.Errorf("Expected Foobar to be new for bob only but got %+v", changes.newlyMatching)
+  }
+}
+
+func TestCarryForward(t *testing.T) {
+  price := float32(5)
+  previous := jsonReport{
+    Matching: []jsonReportGame{{Name: "Errored", SteamId: 1, Price: &price}, {Name: "Fetched", SteamId: 2, Price: &price}, {Name: "Removed", SteamId: 4, Price: &price}},
+    Other: []jsonReportGame{{Name: "Skipped", SteamId: 3, Price: &price}, {Name: "Series game", Entry: "Series", SteamId: 5, Price: &price}},
+  }
+  // Errored couldn't be fetched, Skipped and Series were filtered out by -tags and Removed
+  // was removed from the watchlist.
+  watched := newWatchedEntries([]gameCriteria{newGameCriteria("errored", nil), newGameCriteria("Fetched", nil), newGameCriteria("Skipped", nil), newGameCriteria("Series", nil)})
+  current := jsonReport{
+    Other: []jsonReportGame{{Name: "Fetched", SteamId: 2, Price: &price}},
+    Errors: []jsonReportError{{Name: "Errored", Error: "Unexpected HTTP status 503"}},
+  }
+  snapshot := carryForward(previous, current, watched)
+  if len(snapshot.Matching) != 1 || snapshot.Matching[0].Name != "Errored" {
+    t.Errorf("Expected Errored to stay under target and Removed to be dropped but got %+v", snapshot.Matching)
+  }
+  if len(snapshot.Other) != 3 || snapshot.Other[0].Name != "Fetched" || snapshot.Other[1].Name != "Skipped" || snapshot.Other[2].Name != "Series game" {
+    t.Errorf("Expected Fetched to be updated and Skipped and Series game to be kept but got %+v", snapshot.Other)
+  }
+
+  // Errored is fetched again: it isn't new. Removed is added back: it is.
+  next := jsonReport{Matching: []jsonReportGame{{Name: "Errored", SteamId: 1, Price: &price}, {Name: "Removed", SteamId: 4, Price: &price}}}
+  changes := diffSnapshots(snapshot, next)
+  if len(changes.newlyMatching) != 1 || changes.newlyMatching[0].game.Name != "Removed" {
+    t.Errorf("Expected Removed to be newly matching but got %+v", changes.newlyMatching)
+  }
+}
+
+func TestSnapshotRoundTrip(t *testing.T) {
+  fileName := filepath.Join(t.TempDir(), "snapshot.json")
+  snapshot, err := loadSnapshot(fileName)
+  if err != nil || snapshot != nil {
+    t.Fatalf("Expected no snapshot for a missing file but got %+v (err = %+v)", snapshot, err)
+  }
+
+  price := float32(4.99)
+  saved := jsonReport{Version: cReportVersion, GeneratedAt: "2026-10-18T00:00:00Z", Matching: []jsonReportGame{{Name: "Foobar", SteamId: 1, Price: &price}}}
+  err = saveSnapshot(fileName, saved)
+  if err != nil {
+    t.Fatalf("Error saving the snapshot (err = %+v)", err)
+  }
+  snapshot, err = loadSnapshot(fileName)
+  if err != nil {
+    t.Fatalf("Error loading the snapshot (err = %+v)", err)
+  }
+  if !reflect.DeepEqual(*snapshot, saved) {
+    t.Errorf("Expected %+v but got %+v", saved, *snapshot)
+  }
+}
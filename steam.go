@@ -2,10 +2,12 @@
 package main
 
 import (
+  "encoding/json"
   "errors"
   "io"
   "fmt"
   "net/http"
+  "net/url"
   "os"
   "strconv"
   "strings"
@@ -18,9 +20,18 @@ const (
   cGameBundleIdAttr string = "data-ds-bundleid"
   cGameClassNameAttr string = "match_name"
   cGameClassPriceAttr string = "match_price"
+  cSteamStoreHost string = "store.steampowered.com/"
   cSteamSearchURLMissingKeyword string = "https://store.steampowered.com/search/suggest?term=%s&f=games&cc=US"
+  cSteamAppDetailsURLMissingId string = "https://store.steampowered.com/api/appdetails?appids=%d&cc=US"
+  // The parameters are the search field (term, developer or publisher), the value and the index of the first result.
+  // Steam returns at most 100 results per page.
+  cSteamQueryURLMissingFieldValueAndStart string = "https://store.steampowered.com/search/results/?%s=%s&category1=998&start=%d&count=100&infinite=1&cc=US"
+  cSteamBundleDetailsURLMissingId string = "https://store.steampowered.com/actions/ajaxresolvebundles?bundleids=%d&cc=US&l=english"
 
   cDefaultTargetPrice float32 = 7
+
+  // Series/developer/publisher entries stop after this many games (with a warning).
+  cSteamQueryMaxResults int = 1000
 )
 
 // States for our parser.
@@ -47,7 +58,7 @@ func parseSearchResult(gameName string, reader io.Reader) (error, []Game) {
   parsingState := lookingForGame
 
   games := []Game{};
-  parsedGame := Game{"", -1, "", SteamInfo{0, 0, -1}, FanaticalInfo{-1, ""}, GreenManGamingInfo{-1, ""}, HumbleBundleInfo{-1, ""}, LoadedInfo{-1, ""}};
+  parsedGame := newGame()
 
   tokenizer := html.NewTokenizer(reader)
   for {
@@ -177,7 +188,7 @@ func parseSearchResult(gameName string, reader io.Reader) (error, []Game) {
             games = append(games, parsedGame)
           }
 
-          parsedGame = Game{"", -1, "", SteamInfo{0, 0, -1}, FanaticalInfo{-1, ""}, GreenManGamingInfo{-1, ""}, HumbleBundleInfo{-1, ""}, LoadedInfo{-1, ""}};
+          parsedGame = newGame()
           parsingState = lookingForGame
         }
     }
@@ -199,7 +210,46 @@ func selectBestMatchingGame(name string, games []Game) *Game {
 }
 
 
-func SearchGameOnSteam(name string) (error, *Game) {
+// Parses a Steam reference into pinned ids.
+// References are "app/<id>", "bundle/<id>" or a full store URL
+// (e.g. https://store.steampowered.com/app/<id>/Some_Name/).
+func parseSteamReference(input string) (pinnedIds, bool) {
+  ref := strings.TrimSpace(input)
+  ref = strings.TrimPrefix(ref, "https://")
+  ref = strings.TrimPrefix(ref, "http://")
+  ref = strings.TrimPrefix(ref, cSteamStoreHost)
+
+  kind, rest, found := strings.Cut(ref, "/")
+  if !found {
+    return pinnedIds{}, false
+  }
+  // Drop the optional trailing name and query parameters.
+  if end := strings.IndexAny(rest, "/?#"); end != -1 {
+    rest = rest[:end]
+  }
+  id, err := strconv.Atoi(rest)
+  if err != nil || id <= 0 {
+    return pinnedIds{}, false
+  }
+
+  switch kind {
+    case "app":
+      return pinnedIds{steamId: id}, true
+    case "bundle":
+      return pinnedIds{steamBundleId: id}, true
+  }
+  return pinnedIds{}, false
+}
+
+func SearchGameOnSteam(name string, pinned pinnedIds) (error, *Game) {
+  // Pinned ids skip the name search entirely.
+  if pinned.steamId != 0 {
+    return fetchSteamApp(pinned.steamId)
+  }
+  if pinned.steamBundleId != 0 {
+    return fetchSteamBundle(pinned.steamBundleId)
+  }
+
   // Steam uses '+' as delimiter for words in their URL.
   searchURL := fmt.Sprintf(cSteamSearchURLMissingKeyword, strings.Join(strings.Split(name, " "), "+"))
   resp, err := http.Get(searchURL)
@@ -214,9 +264,268 @@ func SearchGameOnSteam(name string) (error, *Game) {
   }
 
   if len(games) == 0 {
-    panic(fmt.Sprintf("Couldn't find a matching game for %s (did you mistype the name?)", name))
+    if debugFlag {
+      fmt.Printf("[Steam] No result for \"%s\"\n", name)
+    }
+    return nil, nil
   }
 
   return nil, selectBestMatchingGame(name, games)
 }
 
+type steamAppPrice struct {
+  // Prices in cents.
+  Initial int
+  Final int
+}
+
+type steamAppReleaseDate struct {
+  ComingSoon bool `json:"coming_soon"`
+}
+
+type steamAppData struct {
+  Name string
+  IsFree bool `json:"is_free"`
+  // Missing for unreleased games.
+  Price *steamAppPrice `json:"price_overview"`
+  ReleaseDate steamAppReleaseDate `json:"release_date"`
+}
+
+type steamAppDetails struct {
+  Success bool
+  Data steamAppData
+}
+
+type steamBundleDetails struct {
+  Name string
+  // Price in cents.
+  FinalPrice int `json:"final_price"`
+}
+
+func fetchSteamApp(id int) (error, *Game) {
+  resp, err := http.Get(fmt.Sprintf(cSteamAppDetailsURLMissingId, id))
+  if err != nil {
+    return err, nil
+  }
+  defer resp.Body.Close()
+  err = checkHTTPStatus(resp)
+  if err != nil {
+    return err, nil
+  }
+
+  // The response is keyed by the (stringified) app id.
+  var parsedResp map[string]steamAppDetails
+  err = json.NewDecoder(resp.Body).Decode(&parsedResp)
+  if err != nil {
+    return err, nil
+  }
+  if debugFlag {
+    fmt.Printf("[Steam] Got (parsed) app details: %+v\n", parsedResp)
+  }
+
+  details, found := parsedResp[strconv.Itoa(id)]
+  if !found || !details.Success {
+    return fmt.Errorf("Unknown Steam app %d", id), nil
+  }
+
+  game := newGame()
+  game.name = details.Data.Name
+  game.steam.id = id
+  if details.Data.IsFree {
+    game.steam.price = 0
+  } else if details.Data.Price != nil && !details.Data.ReleaseDate.ComingSoon {
+    game.steam.price = float32(details.Data.Price.Final) / 100
+    game.steam.basePrice = float32(details.Data.Price.Initial) / 100
+  }
+  return nil, &game
+}
+
+func fetchSteamBundle(id int) (error, *Game) {
+  resp, err := http.Get(fmt.Sprintf(cSteamBundleDetailsURLMissingId, id))
+  if err != nil {
+    return err, nil
+  }
+  defer resp.Body.Close()
+  err = checkHTTPStatus(resp)
+  if err != nil {
+    return err, nil
+  }
+
+  var parsedResp []steamBundleDetails
+  err = json.NewDecoder(resp.Body).Decode(&parsedResp)
+  if err != nil {
+    return err, nil
+  }
+  if debugFlag {
+    fmt.Printf("[Steam] Got (parsed) bundle details: %+v\n", parsedResp)
+  }
+
+  if len(parsedResp) != 1 || parsedResp[0].Name == "" {
+    return fmt.Errorf("Unknown Steam bundle %d", id), nil
+  }
+
+  game := newGame()
+  game.name = parsedResp[0].Name
+  game.steam.bundleId = id
+  game.steam.price = float32(parsedResp[0].FinalPrice) / 100
+  return nil, &game
+}
+
+// Watchlist entry that expands to all the Steam games matching it.
+type steamQuery struct {
+  // One of "series", "developer" or "publisher".
+  // Empty for regular entries.
+  kind string
+  value string
+}
+
+// Parses "series: <name>", "developer: <name>" or "publisher: <name>".
+func parseSteamQuery(input string) (steamQuery, bool) {
+  kind, value, found := strings.Cut(input, ":")
+  if !found {
+    return steamQuery{}, false
+  }
+  kind = strings.ToLower(strings.TrimSpace(kind))
+  value = strings.TrimSpace(value)
+  if value == "" {
+    return steamQuery{}, false
+  }
+
+  switch kind {
+    case "series", "developer", "publisher":
+      return steamQuery{kind, value}, true
+  }
+  return steamQuery{}, false
+}
+
+type steamQueryResponse struct {
+  ResultsHTML string `json:"results_html"`
+  TotalCount int `json:"total_count"`
+}
+
+// Extracts the games from the search results' HTML.
+// Each result is an anchor with the app id that contains a <span class="title"> with the name.
+// Also returns the number of results, including the packages and bundles that are skipped,
+// which is the offset of the next page.
+func parseQueryResults(reader io.Reader) (error, []Game, int) {
+  games := []Game{}
+  results := 0
+  parsedGame := newGame()
+  parsingName := false
+
+  tokenizer := html.NewTokenizer(reader)
+  for {
+    tt := tokenizer.Next()
+
+    switch tt {
+      case html.ErrorToken:
+        err := tokenizer.Err()
+        if err != io.EOF {
+          return err, games, results
+        }
+        return nil, games, results
+
+      case html.TextToken:
+        if parsingName {
+          parsedGame.name = strings.TrimSpace(string(tokenizer.Text()))
+          parsingName = false
+        }
+
+      case html.StartTagToken:
+        tn, hasAttr := tokenizer.TagName()
+        tagName := string(tn)
+        for hasAttr {
+          attrName, attrValue, more := tokenizer.TagAttr()
+          hasAttr = more
+          if tagName == "a" && string(attrName) == "class" && strings.Contains(string(attrValue), "search_result_row") {
+            results += 1
+          }
+          if tagName == "a" && string(attrName) == cGameIdAttr {
+            // Packages list several comma-separated app ids, we ignore them.
+            parsedId, err := strconv.Atoi(string(attrValue))
+            if err == nil {
+              parsedGame.steam.id = parsedId
+            }
+          }
+          if tagName == "span" && string(attrName) == "class" && string(attrValue) == "title" && parsedGame.steam.id != 0 {
+            parsingName = true
+          }
+        }
+
+      case html.EndTagToken:
+        tn, _ := tokenizer.TagName()
+        if string(tn) == "a" {
+          if parsedGame.steam.id != 0 && parsedGame.name != "" {
+            games = append(games, parsedGame)
+          }
+          parsedGame = newGame()
+          parsingName = false
+        }
+    }
+  }
+}
+
+// Returns the games (with only their id and name) matching |query|.
+func SearchQueryOnSteam(query steamQuery) (error, []Game) {
+  field := query.kind
+  if field == "series" {
+    // There is no series field so we search the name and filter the results below.
+    field = "term"
+  }
+  games := []Game{}
+  // Pages can overlap when the results change between requests.
+  seen := make(map[int]bool)
+  offset := 0
+  for {
+    searchURL := fmt.Sprintf(cSteamQueryURLMissingFieldValueAndStart, field, url.QueryEscape(query.value), offset)
+    if debugFlag {
+      fmt.Printf("[Steam] Query URL: \"%s\"\n", searchURL)
+    }
+    resp, err := http.Get(searchURL)
+    if err != nil {
+      return err, nil
+    }
+    var parsedResp steamQueryResponse
+    err = checkHTTPStatus(resp)
+    if err == nil {
+      err = json.NewDecoder(resp.Body).Decode(&parsedResp)
+    }
+    resp.Body.Close()
+    if err != nil {
+      return err, nil
+    }
+
+    err, pageGames, results := parseQueryResults(strings.NewReader(parsedResp.ResultsHTML))
+    if err != nil {
+      return err, nil
+    }
+    for _, game := range pageGames {
+      if !seen[game.steam.id] {
+        seen[game.steam.id] = true
+        games = append(games, game)
+      }
+    }
+    offset += results
+    if results == 0 || offset >= parsedResp.TotalCount {
+      break
+    }
+    if offset >= cSteamQueryMaxResults {
+      fmt.Fprintf(os.Stderr, "Only the first %d of the %d Steam results of %s:%s are watched\n", offset, parsedResp.TotalCount, query.kind, query.value)
+      break
+    }
+  }
+  if query.kind != "series" {
+    return nil, games
+  }
+
+  // The term search also matches tags and descriptions.
+  series := normalizeName(query.value)
+  seriesGames := []Game{}
+  for _, game := range games {
+    if strings.Contains(normalizeName(game.name), series) {
+      seriesGames = append(seriesGames, game)
+    }
+  }
+  return nil, seriesGames
+}
+
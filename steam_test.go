@@ -0,0 +1,96 @@
+package main
+
+import (
+  "strings"
+  "testing"
+)
+
+func TestParseSteamReference(t *testing.T) {
+  tt := []struct {
+    name string
+    input string
+    expected pinnedIds
+    expectedFound bool
+  } {
+    {"Game name", "Foobar", pinnedIds{}, false},
+    {"Game name with slash", "Foo/Bar", pinnedIds{}, false},
+    {"App reference", "app/12345", pinnedIds{steamId: 12345}, true},
+    {"Bundle reference", "bundle/678", pinnedIds{steamBundleId: 678}, true},
+    {"Invalid id", "app/foobar", pinnedIds{}, false},
+    {"Store URL", "https://store.steampowered.com/app/12345/Foo_Bar/", pinnedIds{steamId: 12345}, true},
+    {"Store URL without scheme", "store.steampowered.com/bundle/678", pinnedIds{steamBundleId: 678}, true},
+    {"Store URL with query", "https://store.steampowered.com/app/12345?snr=1_5_9__205", pinnedIds{steamId: 12345}, true},
+    {"Other URL", "https://store.steampowered.com/sub/12345", pinnedIds{}, false},
+    {"Surrounding spaces", " app/12345 ", pinnedIds{steamId: 12345}, true},
+  }
+
+  for _, tc := range(tt) {
+    t.Run(tc.name, func(t *testing.T) {
+      output, found := parseSteamReference(tc.input)
+      if found != tc.expectedFound || output != tc.expected {
+        t.Errorf("Expected (%+v, %v) but got (%+v, %v)", tc.expected, tc.expectedFound, output, found)
+        return
+      }
+    })
+  }
+}
+
+func TestParseSteamQuery(t *testing.T) {
+  tt := []struct {
+    name string
+    input string
+    expected steamQuery
+    expectedFound bool
+  } {
+    {"Game name", "Foobar", steamQuery{}, false},
+    {"Game name with colon", "Foobar: The Game", steamQuery{}, false},
+    {"Series", "series: Foobar", steamQuery{"series", "Foobar"}, true},
+    {"Developer", "developer:Foo Studio", steamQuery{"developer", "Foo Studio"}, true},
+    {"Publisher", "Publisher: Bar", steamQuery{"publisher", "Bar"}, true},
+    {"Missing value", "series: ", steamQuery{}, false},
+  }
+
+  for _, tc := range(tt) {
+    t.Run(tc.name, func(t *testing.T) {
+      output, found := parseSteamQuery(tc.input)
+      if found != tc.expectedFound || output != tc.expected {
+        t.Errorf("Expected (%+v, %v) but got (%+v, %v)", tc.expected, tc.expectedFound, output, found)
+        return
+      }
+    })
+  }
+}
+
+func TestParseQueryResults(t *testing.T) {
+  results := `
+<a href="https://store.steampowered.com/app/638970/Yakuza_0/" data-ds-appid="638970" class="search_result_row">
+  <div class="search_name"><span class="title">Yakuza 0</span></div>
+</a>
+<a href="https://store.steampowered.com/sub/1234/" data-ds-appid="638970,834530" class="search_result_row">
+  <div class="search_name"><span class="title">Yakuza Bundle</span></div>
+</a>
+<a href="https://store.steampowered.com/bundle/5678/" data-ds-bundleid="5678" class="search_result_row ds_collapse_flag">
+  <div class="search_name"><span class="title">Yakuza Complete</span></div>
+</a>
+<a href="https://store.steampowered.com/app/834530/Yakuza_Kiwami/" data-ds-appid="834530" class="search_result_row">
+  <div class="search_name"><span class="title">Yakuza Kiwami</span></div>
+</a>`
+
+  err, games, count := parseQueryResults(strings.NewReader(results))
+  if err != nil {
+    t.Fatalf("Unexpected error %+v", err)
+  }
+  // The package and the bundle count for the offset of the next page.
+  if count != 4 {
+    t.Errorf("Expected 4 results but got %d", count)
+  }
+  if len(games) != 2 {
+    t.Fatalf("Expected 2 games but got %d (%+v)", len(games), games)
+  }
+  if games[0].steam.id != 638970 || games[0].name != "Yakuza 0" {
+    t.Errorf("Unexpected first game: %+v", games[0])
+  }
+  if games[1].steam.id != 834530 || games[1].name != "Yakuza Kiwami" {
+    t.Errorf("Unexpected second game: %+v", games[1])
+  }
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+  "encoding/json"
+  "errors"
+  "flag"
+  "fmt"
+  "io/fs"
+  "os"
+  "sort"
+  "strconv"
+)
+
+type steamWishlistItem struct {
+  AppId int `json:"appid"`
+  // Only present in the wishlistdata format.
+  Name string `json:"name"`
+  // 1 is the top of the wishlist, 0 is unranked.
+  Priority int `json:"priority"`
+}
+
+type steamOwnedGame struct {
+  AppId int `json:"appid"`
+  Name string `json:"name"`
+}
+
+// Parses a wishlist as served by Steam, either:
+// * the store's wishlistdata: {"<appid>": {"name": "...", "priority": 1, ...}, ...}
+// * IWishlistService/GetWishlist: {"response": {"items": [{"appid": 123, "priority": 1, ...}]}}
+// The items are sorted by priority (unranked last).
+func parseSteamWishlist(data []byte) ([]steamWishlistItem, error) {
+  var document map[string]json.RawMessage
+  err := json.Unmarshal(data, &document)
+  if err != nil {
+    return nil, err
+  }
+
+  items := []steamWishlistItem{}
+  if rawResponse, found := document["response"]; found {
+    var response struct {
+      Items []steamWishlistItem `json:"items"`
+    }
+    err = json.Unmarshal(rawResponse, &response)
+    if err != nil {
+      return nil, err
+    }
+    items = response.Items
+  } else {
+    for key, rawItem := range document {
+      appId, err := strconv.Atoi(key)
+      if err != nil {
+        return nil, fmt.Errorf("Invalid app id \"%s\" in wishlist", key)
+      }
+      var item steamWishlistItem
+      err = json.Unmarshal(rawItem, &item)
+      if err != nil {
+        return nil, err
+      }
+      item.AppId = appId
+      items = append(items, item)
+    }
+  }
+
+  sort.Slice(items, func(i, j int) bool {
+    if items[i].Priority != items[j].Priority {
+      if items[i].Priority == 0 || items[j].Priority == 0 {
+        return items[j].Priority == 0
+      }
+      return items[i].Priority < items[j].Priority
+    }
+    return items[i].AppId < items[j].AppId
+  })
+  return items, nil
+}
+
+// Parses an owned-games export, either the IPlayerService/GetOwnedGames response
+// ({"response": {"games": [{"appid": 123, ...}]}}) or a bare array of games.
+// Returns the owned app ids.
+func parseSteamOwnedGames(data []byte) (map[int]bool, error) {
+  var games []steamOwnedGame
+  if json.Unmarshal(data, &games) != nil {
+    var document struct {
+      Response struct {
+        Games []steamOwnedGame `json:"games"`
+      } `json:"response"`
+    }
+    err := json.Unmarshal(data, &document)
+    if err != nil {
+      return nil, err
+    }
+    games = document.Response.Games
+  }
+
+  owned := make(map[int]bool)
+  for _, game := range games {
+    owned[game.AppId] = true
+  }
+  return owned, nil
+}
+
+// Converts the wishlist into watchlist entries, dropping the owned and already watched games.
+func importSteamWishlist(wishlist []steamWishlistItem, owned map[int]bool, existing []gameCriteria) (entries []watchlistEntry, skippedOwned int, skippedWatched int) {
+  watchedIds := make(map[int]bool)
+  watchedNames := make(map[string]bool)
+  for _, criteria := range existing {
+    if criteria.pinned.steamId != 0 {
+      watchedIds[criteria.pinned.steamId] = true
+    }
+    watchedNames[normalizeName(criteria.name)] = true
+  }
+
+  ranked := 0
+  for _, item := range wishlist {
+    if item.Priority > 0 {
+      ranked += 1
+    }
+  }
+
+  entries = []watchlistEntry{}
+  for _, item := range wishlist {
+    if owned[item.AppId] {
+      skippedOwned += 1
+      continue
+    }
+    if watchedIds[item.AppId] || (item.Name != "" && watchedNames[normalizeName(item.Name)]) {
+      skippedWatched += 1
+      continue
+    }
+    watchedIds[item.AppId] = true
+
+    // Steam ranks from 1 (top) while our priority is higher for more important games.
+    priority := 0
+    if item.Priority > 0 {
+      priority = ranked - item.Priority + 1
+    }
+    entries = append(entries, watchlistEntry{Name: item.Name, Pinned: &watchlistPinnedIds{Steam: item.AppId}, Priority: priority})
+  }
+  return entries, skippedOwned, skippedWatched
+}
+
+// watcher import-steam -wishlist <file> [-owned <file>] -file <watchlist>
+func runImportSteam(args []string) error {
+  flags := flag.NewFlagSet("import-steam", flag.ExitOnError)
+  wishlistFile := flags.String("wishlist", "", "Steam wishlist JSON")
+  ownedFile := flags.String("owned", "", "Steam owned games JSON (optional)")
+  watchlistFile := flags.String("file", "", "Watchlist to merge into (created if missing)")
+  flags.Parse(args)
+
+  if *wishlistFile == "" || *watchlistFile == "" {
+    flags.Usage()
+    return errors.New("-wishlist and -file are mandatory")
+  }
+
+  data, err := os.ReadFile(*wishlistFile)
+  if err != nil {
+    return err
+  }
+  wishlist, err := parseSteamWishlist(data)
+  if err != nil {
+    return fmt.Errorf("Invalid wishlist=%s (err = %+v)", *wishlistFile, err)
+  }
+
+  owned := make(map[int]bool)
+  if *ownedFile != "" {
+    data, err = os.ReadFile(*ownedFile)
+    if err != nil {
+      return err
+    }
+    owned, err = parseSteamOwnedGames(data)
+    if err != nil {
+      return fmt.Errorf("Invalid owned games=%s (err = %+v)", *ownedFile, err)
+    }
+  }
+
+  existing := []gameCriteria{}
+  _, err = os.Stat(*watchlistFile)
+  if err == nil {
+    existing, err = parseWatchlistFile(*watchlistFile)
+    if err != nil {
+      return err
+    }
+  } else if !errors.Is(err, fs.ErrNotExist) {
+    return err
+  }
+
+  entries, skippedOwned, skippedWatched := importSteamWishlist(wishlist, owned, existing)
+  if len(entries) > 0 {
+    err = appendToWatchlist(*watchlistFile, entries)
+    if err != nil {
+      return err
+    }
+  }
+  fmt.Printf("Added %d game(s) to %s (skipped %d owned and %d already watched)\n", len(entries), *watchlistFile, skippedOwned, skippedWatched)
+  return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+  "reflect"
+  "testing"
+)
+
+func TestParseSteamWishlist(t *testing.T) {
+  tt := []struct {
+    name string
+    input string
+  } {
+    {"wishlistdata", `{"30": {"name": "Baz", "priority": 0}, "20": {"name": "Bar", "priority": 2}, "10": {"name": "Foo", "priority": 1}}`},
+    {"GetWishlist", `{"response": {"items": [{"appid": 30, "priority": 0}, {"appid": 20, "priority": 2}, {"appid": 10, "priority": 1}]}}`},
+  }
+
+  for _, tc := range(tt) {
+    t.Run(tc.name, func(t *testing.T) {
+      items, err := parseSteamWishlist([]byte(tc.input))
+      if err != nil {
+        t.Fatalf("Unexpected error %+v", err)
+      }
+      ids := []int{}
+      for _, item := range items {
+        ids = append(ids, item.AppId)
+      }
+      if !reflect.DeepEqual(ids, []int{10, 20, 30}) {
+        t.Errorf("Expected the items sorted by priority but got %+v", items)
+      }
+    })
+  }
+}
+
+func TestParseSteamOwnedGames(t *testing.T) {
+  for _, input := range []string{`{"response": {"game_count": 2, "games": [{"appid": 10}, {"appid": 20}]}}`, `[{"appid": 10}, {"appid": 20}]`} {
+    owned, err := parseSteamOwnedGames([]byte(input))
+    if err != nil {
+      t.Fatalf("Unexpected error %+v", err)
+    }
+    if !reflect.DeepEqual(owned, map[int]bool{10: true, 20: true}) {
+      t.Errorf("Unexpected owned games %+v for %s", owned, input)
+    }
+  }
+}
+
+func TestImportSteamWishlist(t *testing.T) {
+  wishlist := []steamWishlistItem{
+    steamWishlistItem{10, "Foo", 1},
+    steamWishlistItem{20, "Bar", 2},
+    steamWishlistItem{30, "Baz", 3},
+    steamWishlistItem{40, "", 0},
+    steamWishlistItem{50, "Watched By Name", 0},
+  }
+  owned := map[int]bool{20: true}
+  existing := []gameCriteria{
+    newGameCriteria("app/30", defaultTarget),
+    newGameCriteria("Watched by name", defaultTarget),
+  }
+
+  entries, skippedOwned, skippedWatched := importSteamWishlist(wishlist, owned, existing)
+  expected := []watchlistEntry{
+    watchlistEntry{Name: "Foo", Pinned: &watchlistPinnedIds{Steam: 10}, Priority: 3},
+    watchlistEntry{Pinned: &watchlistPinnedIds{Steam: 40}},
+  }
+  if !reflect.DeepEqual(entries, expected) {
+    t.Errorf("Expected %+v but got %+v", expected, entries)
+  }
+  if skippedOwned != 1 || skippedWatched != 2 {
+    t.Errorf("Expected 1 owned and 2 watched games skipped but got %d and %d", skippedOwned, skippedWatched)
+  }
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+  "fmt"
+  "io"
+  "strings"
+)
+
+// Per-store price table for -format table.
+
+// Column titles of the stores, in the allStores order.
+var storeTitles = map[string]string{
+  "steam": "Steam",
+  "fanatical": "Fanatical",
+  "humblebundle": "Humble Bundle",
+  "gmg": "GMG",
+  "loaded": "Loaded",
+}
+
+// Returns "$4.99 -75%" with the discount against the Steam base price when known.
+func formatOfferCell(price float32, basePrice float32) string {
+  cell := fmt.Sprintf("$%.2f", price)
+  if basePrice > 0 && price < basePrice {
+    cell += fmt.Sprintf(" -%.0f%%", (1 - price / basePrice) * 100)
+  }
+  return cell
+}
+
+// Returns the saving of the cheapest offer against Steam.
+func formatSteamSaving(game Game, steamPrice float32, hasSteam bool) string {
+  if game.backend == "" {
+    return ""
+  }
+  if !hasSteam {
+    return "not on Steam"
+  }
+  if game.backend == "steam" || steamPrice <= game.minPrice {
+    return "-"
+  }
+  saving := steamPrice - game.minPrice
+  return fmt.Sprintf("$%.2f (%.0f%%)", saving, saving / steamPrice * 100)
+}
+
+// Returns the cells of |game|: name, one price per store, saving and target.
+// The cheapest offer is marked with '*'.
+func priceTableRow(game Game) []string {
+  cells := make(map[string]string)
+  steamPrice := float32(-1)
+  hasSteam := false
+  for _, offer := range game.offers() {
+    cell := formatOfferCell(offer.price, game.steam.basePrice)
+    if offer.backend == game.backend {
+      cell = "*" + cell
+    }
+    cells[offer.backend] = cell
+    if offer.backend == "steam" {
+      steamPrice = offer.price
+      hasSteam = true
+    }
+  }
+
+  row := []string{game.name}
+  for _, store := range allStores {
+    cell, found := cells[store]
+    if !found {
+      cell = "-"
+    }
+    row = append(row, cell)
+  }
+  target := game.criteria.target.String()
+  if game.matchedCondition != "" {
+    target = game.matchedCondition
+  }
+  return append(row, formatSteamSaving(game, steamPrice, hasSteam), target)
+}
+
+// Prints |games| as a table aligned on all the games, with the group and tag headers between the rows.
+// The columns are measured in terminal cells (see displayWidth) so wide names stay aligned.
+func printPriceTable(writer io.Writer, games []Game) {
+  header := []string{"Game"}
+  for _, store := range allStores {
+    header = append(header, storeTitles[store])
+  }
+  header = append(header, "Saving vs Steam", "Target")
+
+  rows := [][]string{}
+  for _, game := range games {
+    rows = append(rows, priceTableRow(game))
+  }
+
+  widths := make([]int, len(header))
+  for _, row := range append([][]string{header}, rows...) {
+    for column, cell := range row {
+      if width := displayWidth(cell); width > widths[column] {
+        widths[column] = width
+      }
+    }
+  }
+
+  printRow := func(row []string) {
+    padded := []string{}
+    for column, cell := range row {
+      padded = append(padded, padRight(cell, widths[column]))
+    }
+    fmt.Fprintln(writer, strings.TrimRight(strings.Join(padded, " | "), " "))
+  }
+
+  printRow(header)
+  separators := []string{}
+  for _, width := range widths {
+    separators = append(separators, strings.Repeat("-", width))
+  }
+  fmt.Fprintln(writer, strings.Join(separators, "-+-"))
+  for i, row := range rows {
+    for _, groupHeader := range groupHeaders(games, i) {
+      fmt.Fprintln(writer, groupHeader)
+    }
+    printRow(row)
+  }
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+  "bytes"
+  "reflect"
+  "strings"
+  "testing"
+)
+
+func TestPriceTableRow(t *testing.T) {
+  output := newTestReportOutput()
+  steamOnly := pricedGame(5, -1)
+  steamOnly.name = "Steam only"
+  steamOnly.criteria.target = defaultTarget
+
+  tt := []struct {
+    name string
+    game Game
+    expected []string
+  } {
+    {"Cheaper than Steam", output.matchingGames[0], []string{"Foobar", "$9.99 -50%", "*$4.99 -75%", "-", "-", "-", "$5.00 (50%)", "under $5.00"}},
+    {"No offer", output.otherGames[0], []string{"Barfoo", "-", "-", "-", "-", "-", "", "$7.00"}},
+    {"Steam is the cheapest", steamOnly, []string{"Steam only", "*$5.00", "-", "-", "-", "-", "-", "$7.00"}},
+  }
+
+  for _, tc := range(tt) {
+    t.Run(tc.name, func(t *testing.T) {
+      row := priceTableRow(tc.game)
+      if !reflect.DeepEqual(row, tc.expected) {
+        t.Errorf("Expected %q but got %q", tc.expected, row)
+      }
+    })
+  }
+}
+
+func TestPrintPriceTable(t *testing.T) {
+  output := newTestReportOutput()
+  games := append(output.matchingGames, output.otherGames...)
+  games[1].criteria.group = "series: Bar"
+
+  var buf bytes.Buffer
+  printPriceTable(&buf, games)
+  lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+  if len(lines) != 5 || lines[3] != "--- series: Bar ---" {
+    t.Fatalf("Unexpected table\n%s", buf.String())
+  }
+  // The columns are aligned across the rows, in terminal cells.
+  games[0].name = "大神 絶景版"
+  buf.Reset()
+  printPriceTable(&buf, games)
+  lines = strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+  for _, line := range []string{lines[0], lines[2], lines[4]} {
+    if displayWidth(line[:strings.Index(line, "|")]) != strings.Index(lines[1], "+") {
+      t.Errorf("Misaligned table\n%s", buf.String())
+    }
+  }
+}
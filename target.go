@@ -0,0 +1,149 @@
+package main
+
+import (
+  "errors"
+  "fmt"
+  "strconv"
+  "strings"
+)
+
+// Kinds of target conditions.
+const (
+  // The cheapest offer is under an absolute price.
+  belowPrice = iota
+  // The cheapest offer is at least some percent off the Steam base price.
+  percentOffBase = iota
+  // The cheapest offer is at or below the lowest price recorded in the history.
+  atHistoricalLow = iota
+)
+
+type targetCondition struct {
+  kind int
+  // Price for belowPrice, percentage for percentOffBase and unused for atHistoricalLow.
+  value float32
+}
+
+// A target is met as soon as one of its conditions is.
+type priceTarget []targetCondition
+
+// Used for entries without a target. Overridden by -target.
+var defaultTarget = priceTarget{targetCondition{belowPrice, cDefaultTargetPrice}}
+
+// Parses a target expression: '|' separated conditions among
+// a price ("10" or "$10"), a discount off the Steam base price ("75%")
+// or "low" for the historical low.
+func parseTarget(expr string) (priceTarget, error) {
+  target := priceTarget{}
+  for _, conditionExpr := range strings.Split(expr, "|") {
+    conditionExpr = strings.TrimSpace(conditionExpr)
+    if strings.EqualFold(conditionExpr, "low") {
+      target = append(target, targetCondition{atHistoricalLow, 0})
+      continue
+    }
+
+    if strings.HasSuffix(conditionExpr, "%") {
+      percent, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(conditionExpr, "%")), /*bitSize=*/32)
+      if err != nil || percent <= 0 || percent > 100 {
+        return nil, fmt.Errorf("Invalid discount \"%s\"", conditionExpr)
+      }
+      target = append(target, targetCondition{percentOffBase, float32(percent)})
+      continue
+    }
+
+    price, err := strconv.ParseFloat(strings.TrimPrefix(conditionExpr, "$"), /*bitSize=*/32)
+    if err != nil || price < 0 {
+      return nil, fmt.Errorf("Invalid target \"%s\"", conditionExpr)
+    }
+    target = append(target, targetCondition{belowPrice, float32(price)})
+  }
+
+  if len(target) == 0 {
+    return nil, errors.New("Empty target")
+  }
+  return target, nil
+}
+
+func (t priceTarget) String() string {
+  conditions := []string{}
+  for _, condition := range t {
+    switch condition.kind {
+      case belowPrice:
+        conditions = append(conditions, fmt.Sprintf("$%.2f", condition.value))
+      case percentOffBase:
+        conditions = append(conditions, fmt.Sprintf("%v%% off", condition.value))
+      case atHistoricalLow:
+        conditions = append(conditions, "historical low")
+    }
+  }
+  return strings.Join(conditions, " or ")
+}
+
+func (t priceTarget) needsBasePrice() bool {
+  for _, condition := range t {
+    if condition.kind == percentOffBase {
+      return true
+    }
+  }
+  return false
+}
+
+func (t priceTarget) needsHistory() bool {
+  for _, condition := range t {
+    if condition.kind == atHistoricalLow {
+      return true
+    }
+  }
+  return false
+}
+
+// "low" conditions can't be met without the -history file: reject them rather than never firing.
+func checkTargetHistory(name string, target priceTarget) error {
+  if target.needsHistory() && historyFlag == "" {
+    return fmt.Errorf("The target of \"%s\" (%v) needs -history", name, target)
+  }
+  return nil
+}
+
+// Returns the highest price under which the target is met,
+// false if the target has no price condition.
+func (t priceTarget) highestPrice() (float32, bool) {
+  highest := float32(-1)
+  for _, condition := range t {
+    if condition.kind == belowPrice && condition.value > highest {
+      highest = condition.value
+    }
+  }
+  return highest, highest >= 0
+}
+
+// Returns a description of the first condition met by |game|, or "" if none is.
+// |historicalLow| is the lowest recorded price before this run (-1 if unknown).
+func (t priceTarget) check(game Game, historicalLow float32) string {
+  // No (allowed) offer or unreleased.
+  if game.backend == "" || game.minPrice < 0 {
+    return ""
+  }
+
+  for _, condition := range t {
+    switch condition.kind {
+      case belowPrice:
+        if game.minPrice < condition.value {
+          return fmt.Sprintf("under $%.2f", condition.value)
+        }
+      case percentOffBase:
+        // Bundles have no base price, see processGame's warning.
+        basePrice := game.steam.basePrice
+        if basePrice <= 0 {
+          continue
+        }
+        if game.minPrice <= basePrice * (1 - condition.value / 100) {
+          return fmt.Sprintf("%.0f%% off $%.2f", (1 - game.minPrice / basePrice) * 100, basePrice)
+        }
+      case atHistoricalLow:
+        if historicalLow >= 0 && game.minPrice <= historicalLow {
+          return fmt.Sprintf("at historical low of $%.2f", historicalLow)
+        }
+    }
+  }
+  return ""
+}
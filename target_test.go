@@ -0,0 +1,112 @@
+package main
+
+import (
+  "reflect"
+  "testing"
+)
+
+func TestParseTarget(t *testing.T) {
+  tt := []struct {
+    name string
+    expr string
+    expected priceTarget
+    expectError bool
+  } {
+    {"Price", "10", priceTarget{targetCondition{belowPrice, 10}}, false},
+    {"Price with currency", " $9.99 ", priceTarget{targetCondition{belowPrice, 9.99}}, false},
+    {"Discount", "75%", priceTarget{targetCondition{percentOffBase, 75}}, false},
+    {"Historical low", "Low", priceTarget{targetCondition{atHistoricalLow, 0}}, false},
+    {"Several conditions", "5|40%|low", priceTarget{targetCondition{belowPrice, 5}, targetCondition{percentOffBase, 40}, targetCondition{atHistoricalLow, 0}}, false},
+    {"Invalid price", "ten", nil, true},
+    {"Negative price", "-1", nil, true},
+    {"Invalid discount", "150%", nil, true},
+    {"Empty condition", "10|", nil, true},
+  }
+
+  for _, tc := range(tt) {
+    t.Run(tc.name, func(t *testing.T) {
+      output, err := parseTarget(tc.expr)
+      if tc.expectError {
+        if err == nil {
+          t.Errorf("Expected an error but got %+v", output)
+        }
+        return
+      }
+      if err != nil || !reflect.DeepEqual(output, tc.expected) {
+        t.Errorf("Expected %+v but got %+v (err = %+v)", tc.expected, output, err)
+      }
+    })
+  }
+}
+
+func pricedGame(minPrice float32, basePrice float32) Game {
+  game := newGame()
+  game.steam.price = minPrice
+  game.steam.basePrice = basePrice
+  game.minPrice = minPrice
+  game.backend = "steam"
+  return game
+}
+
+func TestTargetCheck(t *testing.T) {
+  tt := []struct {
+    name string
+    target string
+    game Game
+    historicalLow float32
+    expected string
+  } {
+    {"Under price", "10", pricedGame(5, 20), -1, "under $10.00"},
+    {"Over price", "10", pricedGame(10, 20), -1, ""},
+    {"Enough discount", "75%", pricedGame(5, 20), -1, "75% off $20.00"},
+    {"Not enough discount", "80%", pricedGame(5, 20), -1, ""},
+    {"Unknown base price", "10%", pricedGame(5, -1), -1, ""},
+    {"At historical low", "low", pricedGame(5, 20), 5, "at historical low of $5.00"},
+    {"Above historical low", "low", pricedGame(5, 20), 4, ""},
+    {"No history", "low", pricedGame(5, 20), -1, ""},
+    {"First matching condition", "1|50%", pricedGame(5, 20), -1, "75% off $20.00"},
+    {"Unreleased game", "10", pricedGame(-1, -1), -1, ""},
+  }
+
+  for _, tc := range(tt) {
+    t.Run(tc.name, func(t *testing.T) {
+      target, err := parseTarget(tc.target)
+      if err != nil {
+        t.Fatalf("Invalid target %s (err = %+v)", tc.target, err)
+      }
+      output := target.check(tc.game, tc.historicalLow)
+      if output != tc.expected {
+        t.Errorf("Expected \"%s\" but got \"%s\"", tc.expected, output)
+      }
+    })
+  }
+}
+
+func TestCheckTargetHistory(t *testing.T) {
+  defer func(history string) { historyFlag = history }(historyFlag)
+
+  tt := []struct {
+    name string
+    target string
+    history string
+    expectedErr bool
+  } {
+    {"Price without history", "10", "", false},
+    {"Low without history", "10|low", "", true},
+    {"Low with history", "low", "history.json", false},
+  }
+
+  for _, tc := range(tt) {
+    t.Run(tc.name, func(t *testing.T) {
+      historyFlag = tc.history
+      target, err := parseTarget(tc.target)
+      if err != nil {
+        t.Fatalf("Invalid target %s (err = %+v)", tc.target, err)
+      }
+      err = checkTargetHistory("Foobar", target)
+      if (err != nil) != tc.expectedErr {
+        t.Errorf("Expected error = %v but got %+v", tc.expectedErr, err)
+      }
+    })
+  }
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+  "fmt"
+  "io"
+  "os"
+  "path/filepath"
+  "strings"
+  "text/template"
+  "time"
+)
+
+// User-supplied report templates (-template), rendered with text/template.
+//
+// The template is executed on the JSON report (see jsonReport in report.go):
+// .GeneratedAt, .Unreleased, .Matching, .Other (in the order of the text report)
+// and .Errors. Each game has .Name, .Price, .URL, .Backend, .Target,
+// .MatchedCondition, .Stores (every allowed store's .Store, .Price and .URL)...
+//
+// Example listing the games under target in Markdown:
+// {{range .Matching}}* [{{.Name}}]({{.URL}}): {{money .Price}} ({{.MatchedCondition}})
+// {{end}}
+var templateFuncs = template.FuncMap{
+  // Formats a price as "$4.99". Missing prices (nil) are formatted as "-".
+  "money": func(price interface{}) (string, error) {
+    switch value := price.(type) {
+      case float32:
+        return fmt.Sprintf("$%.2f", value), nil
+      case *float32:
+        if value == nil {
+          return "-", nil
+        }
+        return fmt.Sprintf("$%.2f", *value), nil
+      case float64:
+        return fmt.Sprintf("$%.2f", value), nil
+      case int:
+        return fmt.Sprintf("$%d.00", value), nil
+    }
+    return "", fmt.Errorf("money: unexpected price %v", price)
+  },
+  // Returns the offer of |store| for the game, nil if the store has no offer.
+  "offer": func(game jsonReportGame, store string) *jsonReportOffer {
+    for idx := range game.Stores {
+      if game.Stores[idx].Store == store {
+        return &game.Stores[idx]
+      }
+    }
+    return nil
+  },
+  // Returns the URL of |store| for the game, "" if the store has no offer.
+  "storeURL": func(game jsonReportGame, store string) string {
+    for _, offer := range game.Stores {
+      if offer.Store == store {
+        return offer.URL
+      }
+    }
+    return ""
+  },
+  // Returns the Steam store page of the game.
+  "steamURL": func(game jsonReportGame) string {
+    return Game{steam: SteamInfo{id: game.SteamId, bundleId: game.SteamBundleId}}.steamURL()
+  },
+  // Returns the display name of a store (e.g. "Humble Bundle" for "humblebundle").
+  "storeName": func(store string) string {
+    if title, found := storeTitles[store]; found {
+      return title
+    }
+    return store
+  },
+  "join": strings.Join,
+}
+
+func parseReportTemplate(fileName string) (*template.Template, error) {
+  data, err := os.ReadFile(fileName)
+  if err != nil {
+    return nil, err
+  }
+  return template.New(filepath.Base(fileName)).Funcs(templateFuncs).Parse(string(data))
+}
+
+func writeTemplateReport(writer io.Writer, reportTemplate *template.Template, output *Output, now time.Time) error {
+  return reportTemplate.Execute(writer, newJSONReport(output, now))
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+  "bytes"
+  "os"
+  "path/filepath"
+  "strings"
+  "testing"
+  "time"
+)
+
+func TestWriteTemplateReport(t *testing.T) {
+  fileName := filepath.Join(t.TempDir(), "report.tmpl")
+  content := `{{.GeneratedAt}}
+{{range .Matching}}* [{{.Name}}]({{.URL}}): {{money .Price}} ({{.MatchedCondition}})
+{{range .Stores}}  - {{storeName .Store}}: {{money .Price}} {{.URL}}
+{{end}}{{with offer . "gmg"}}GMG: {{money .Price}}{{else}}  Not on GMG{{end}}
+  Steam: {{steamURL .}} {{storeURL . "fanatical"}}
+{{end}}{{range .Other}}* {{.Name}}: {{money .Price}}{{if .Tags}} [{{join .Tags ", "}}]{{end}}
+{{end}}`
+  err := os.WriteFile(fileName, []byte(content), 0644)
+  if err != nil {
+    t.Fatalf("Unexpected error %+v", err)
+  }
+  reportTemplate, err := parseReportTemplate(fileName)
+  if err != nil {
+    t.Fatalf("Unexpected error %+v", err)
+  }
+
+  output := newTestReportOutput()
+  output.otherGames[0].criteria.tags = []string{"coop", "rpg"}
+  var buf bytes.Buffer
+  err = writeTemplateReport(&buf, reportTemplate, output, time.Date(2020, 5, 6, 10, 0, 0, 0, time.UTC))
+  if err != nil {
+    t.Fatalf("Unexpected error %+v", err)
+  }
+
+  expected := `2020-05-06T10:00:00Z
+* [Foobar](https://www.fanatical.com/en/game/foobar): $4.99 (under $5.00)
+  - Steam: $9.99 https://store.steampowered.com/app/10
+  - Fanatical: $4.99 https://www.fanatical.com/en/game/foobar
+  Not on GMG
+  Steam: https://store.steampowered.com/app/10 https://www.fanatical.com/en/game/foobar
+* Barfoo: - [coop, rpg]
+`
+  if buf.String() != expected {
+    t.Errorf("Expected\n%s\nbut got\n%s", expected, buf.String())
+  }
+}
+
+func TestParseReportTemplateErrors(t *testing.T) {
+  fileName := filepath.Join(t.TempDir(), "invalid.tmpl")
+  os.WriteFile(fileName, []byte("{{range .Matching}}"), 0644)
+  _, err := parseReportTemplate(fileName)
+  if err == nil || !strings.Contains(err.Error(), "invalid.tmpl") {
+    t.Errorf("Expected a parse error but got %+v", err)
+  }
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+  "fmt"
+  "io"
+  "os"
+  "strings"
+  "unicode"
+
+  "golang.org/x/text/width"
+)
+
+// Colorized text report for terminals (-color).
+//
+// The rows are aligned in columns (name, price, store, condition) fitting the
+// terminal width, the names being truncated. The URL of the offer is printed under
+// each row, or with -hyperlinks the names link to it (OSC 8 hyperlinks, which some
+// terminals and multiplexers don't show). Piped output keeps the plain text report.
+//
+// The columns are measured in terminal cells: East Asian wide and fullwidth
+// characters take two cells and combining marks none (see displayWidth).
+
+const (
+  cColorAuto = "auto"
+  cColorAlways = "always"
+  cColorNever = "never"
+)
+
+// Used when the width can't be read from the terminal or $COLUMNS.
+const cDefaultTerminalWidth = 80
+
+// Names are never truncated under this.
+const cMinNameWidth = 16
+
+const (
+  cAnsiReset = "\x1b[0m"
+  cAnsiBold = "\x1b[1m"
+  cAnsiDim = "\x1b[2m"
+  cAnsiRed = "\x1b[31m"
+  cAnsiGreen = "\x1b[32m"
+  cAnsiYellow = "\x1b[33m"
+  cAnsiCyan = "\x1b[36m"
+)
+
+// Colors of the priceClass classes.
+var classColors = map[string]string{
+  "matching": cAnsiGreen,
+  "close": cAnsiYellow,
+  "over": cAnsiRed,
+  "none": cAnsiDim,
+}
+
+func isTerminal(file *os.File) bool {
+  info, err := file.Stat()
+  return err == nil && info.Mode() & os.ModeCharDevice != 0
+}
+
+// -color auto uses the terminal report when stdout is a terminal, unless $NO_COLOR is set or $TERM is "dumb".
+func useTerminalReport(colorRule string) bool {
+  switch colorRule {
+    case cColorAlways:
+      return true
+    case cColorNever:
+      return false
+  }
+  return isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == "" && os.Getenv("TERM") != "dumb"
+}
+
+// Returns the width of the terminal, falling back to $COLUMNS (see terminalSize).
+func terminalWidth(file *os.File) int {
+  if width := terminalSize(file); width > 0 {
+    return width
+  }
+  var width int
+  if _, err := fmt.Sscanf(os.Getenv("COLUMNS"), "%d", &width); err == nil && width > 0 {
+    return width
+  }
+  return cDefaultTerminalWidth
+}
+
+// Returns the number of terminal cells taken by |r|.
+func runeWidth(r rune) int {
+  if unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf) {
+    return 0
+  }
+  switch width.LookupRune(r).Kind() {
+    case width.EastAsianWide, width.EastAsianFullwidth:
+      return 2
+  }
+  return 1
+}
+
+// Returns the number of terminal cells taken by |text|.
+func displayWidth(text string) int {
+  cells := 0
+  for _, r := range text {
+    cells += runeWidth(r)
+  }
+  return cells
+}
+
+// Cuts |text| to |cells| terminal cells, ending with "…" when truncated.
+func truncate(text string, cells int) string {
+  if displayWidth(text) <= cells {
+    return text
+  }
+  ellipsis := "…"
+  if cells <= 1 {
+    ellipsis = ""
+  }
+  available := cells - displayWidth(ellipsis)
+  used := 0
+  for i, r := range text {
+    if used + runeWidth(r) > available {
+      return text[:i] + ellipsis
+    }
+    used += runeWidth(r)
+  }
+  return text
+}
+
+func padRight(text string, cells int) string {
+  return text + strings.Repeat(" ", cells - displayWidth(text))
+}
+
+func padLeft(text string, cells int) string {
+  return strings.Repeat(" ", cells - displayWidth(text)) + text
+}
+
+type terminalRow struct {
+  name string
+  url string
+  price string
+  store string
+  note string
+  // See classColors.
+  class string
+}
+
+func newTerminalRow(game Game, matching bool) terminalRow {
+  if game.steam.price == -1 {
+    return terminalRow{game.name, game.steamURL(), "-", "", "unreleased", "none"}
+  }
+  if game.backend == "" {
+    return terminalRow{game.name, game.url(), "-", "", "no offer in " + strings.Join(game.criteria.stores, ", "), "none"}
+  }
+  note := "target " + game.criteria.target.String()
+  if matching {
+    note = game.matchedCondition
+  }
+  return terminalRow{game.name, game.url(), fmt.Sprintf("$%.2f", game.minPrice), storeTitles[game.backend], note, priceClass(game, matching)}
+}
+
+type terminalRenderer struct {
+  writer io.Writer
+  width int
+  // Links the names to the offers instead of printing the URLs (-hyperlinks).
+  hyperlinks bool
+}
+
+func (r terminalRenderer) style(text string, codes ...string) string {
+  return strings.Join(codes, "") + text + cAnsiReset
+}
+
+func (r terminalRenderer) link(text string, url string) string {
+  return "\x1b]8;;" + url + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+}
+
+func (r terminalRenderer) printTitle(title string, count int) {
+  fmt.Fprintln(r.writer, r.style(fmt.Sprintf("%s (%d)", title, count), cAnsiBold))
+  fmt.Fprintln(r.writer, r.style(strings.Repeat("─", r.width), cAnsiDim))
+}
+
+// Prints |games| in columns, with the group and tag headers between the rows.
+func (r terminalRenderer) printGames(games []Game, matching bool) {
+  rows := []terminalRow{}
+  nameWidth, priceWidth, storeWidth, noteWidth := 0, 0, 0, 0
+  widen := func(width *int, text string) {
+    if textWidth := displayWidth(text); textWidth > *width {
+      *width = textWidth
+    }
+  }
+  for _, game := range games {
+    row := newTerminalRow(game, matching)
+    rows = append(rows, row)
+    widen(&nameWidth, row.name)
+    widen(&priceWidth, row.price)
+    widen(&storeWidth, row.store)
+    widen(&noteWidth, row.note)
+  }
+
+  // The names are cut to fit the notes, down to cMinNameWidth. The notes are cut after that.
+  const separator = "  "
+  available := r.width - priceWidth - storeWidth - 3 * len(separator)
+  if nameWidth > available - noteWidth {
+    nameWidth = available - noteWidth
+    if nameWidth < cMinNameWidth {
+      nameWidth = cMinNameWidth
+    }
+  }
+  if noteWidth > available - nameWidth {
+    noteWidth = available - nameWidth
+    if noteWidth < 0 {
+      noteWidth = 0
+    }
+  }
+
+  for i, row := range rows {
+    for _, groupHeader := range groupHeaders(games, i) {
+      fmt.Fprintln(r.writer, r.style(groupHeader, cAnsiCyan))
+    }
+    name := truncate(row.name, nameWidth)
+    line := name
+    if r.hyperlinks {
+      line = r.link(name, row.url)
+    }
+    line += strings.Repeat(" ", nameWidth - displayWidth(name))
+    line += separator + padLeft(row.price, priceWidth)
+    line += separator + padRight(row.store, storeWidth)
+    line += separator + truncate(row.note, noteWidth)
+    fmt.Fprintln(r.writer, r.style(line, classColors[row.class]))
+    if !r.hyperlinks {
+      // Not truncated so that it stays usable.
+      fmt.Fprintln(r.writer, r.style("  " + row.url, cAnsiDim))
+    }
+  }
+  fmt.Fprintln(r.writer)
+}
+
+func (r terminalRenderer) printReport(output *Output) {
+  if len(output.unreleasedGames) > 0 {
+    r.printTitle("Unreleased games", len(output.unreleasedGames))
+    r.printGames(output.unreleasedGames, false)
+  }
+  if len(output.matchingGames) > 0 {
+    r.printTitle("Games under target", len(output.matchingGames))
+    r.printGames(output.matchingGames, true)
+  }
+  if budgetFlag > 0 {
+    printBasket(selectBasket(output.matchingGames, float32(budgetFlag)), float32(budgetFlag))
+  }
+  r.printTitle("Games over target", len(output.otherGames))
+  r.printGames(output.otherGames, false)
+}
+
+// Shared watchlists get one report per owner.
+func (r terminalRenderer) printTextReport(output *Output) {
+  owners, ownerOutputs := splitOutputByOwner(output)
+  for _, owner := range owners {
+    if owner != "" {
+      fmt.Fprintln(r.writer, r.style("Report for " + owner, cAnsiBold, cAnsiCyan))
+      fmt.Fprintln(r.writer)
+    }
+    r.printReport(ownerOutputs[owner])
+  }
+}
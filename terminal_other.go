@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+// +build !linux,!darwin
+
+package main
+
+import (
+  "os"
+)
+
+// The width comes from $COLUMNS on the other platforms.
+func terminalSize(file *os.File) int {
+  return 0
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+  "bytes"
+  "reflect"
+  "regexp"
+  "strings"
+  "testing"
+)
+
+func TestTruncate(t *testing.T) {
+  tt := []struct {
+    name string
+    text string
+    width int
+    expected string
+  } {
+    {"Fits", "Foobar", 6, "Foobar"},
+    {"Truncated", "Foobar: the game", 8, "Foobar:…"},
+    {"Multi-byte", "Café Tycoon", 5, "Café…"},
+    {"Combining marks", "Cafe\u0301 Tycoon", 5, "Cafe\u0301…"},
+    {"Wide", "大神 絶景版", 5, "大神…"},
+    {"Wide fits", "大神", 4, "大神"},
+    {"Fullwidth", "Ｆｏｏｂａｒ", 6, "Ｆｏ…"},
+    {"Empty", "Foobar", 0, ""},
+  }
+
+  for _, tc := range(tt) {
+    t.Run(tc.name, func(t *testing.T) {
+      if got := truncate(tc.text, tc.width); got != tc.expected {
+        t.Errorf("Expected %q but got %q", tc.expected, got)
+      }
+    })
+  }
+}
+
+// Strips the colors and links.
+var ansiEscapes = regexp.MustCompile("\x1b\\[[0-9;]*m|\x1b\\]8;;[^\x1b]*\x1b\\\\")
+
+func TestTerminalRendererPrintGames(t *testing.T) {
+  cheap := pricedGame(4.99, -1)
+  cheap.name = "Foobar: the very long edition"
+  cheap.criteria.target = priceTarget{targetCondition{belowPrice, 5}}
+  cheap.matchedCondition = "under $5.00"
+  close := pricedGame(14.99, -1)
+  close.name = "Barfoo"
+  close.criteria.target = priceTarget{targetCondition{belowPrice, 12}}
+
+  var buffer bytes.Buffer
+  terminalRenderer{&buffer, 40, true}.printGames([]Game{cheap, close}, false)
+  lines := strings.Split(ansiEscapes.ReplaceAllString(buffer.String(), ""), "\n")
+  expected := []string{
+    "Foobar: the ver…   $4.99  Steam  target…",
+    "Barfoo            $14.99  Steam  target…",
+    "",
+    "",
+  }
+  if !reflect.DeepEqual(lines, expected) {
+    t.Errorf("Expected %q but got %q", expected, lines)
+  }
+  if !strings.Contains(buffer.String(), cAnsiYellow + "\x1b]8;;") {
+    t.Errorf("Expected Barfoo to be colored as close to target:\n%q", buffer.String())
+  }
+
+  // Without -hyperlinks, the URLs are printed and wide names keep the columns aligned.
+  close.name = "大神 絶景版"
+  buffer.Reset()
+  terminalRenderer{&buffer, 40, false}.printGames([]Game{cheap, close}, false)
+  lines = strings.Split(ansiEscapes.ReplaceAllString(buffer.String(), ""), "\n")
+  expected = []string{
+    "Foobar: the ver…   $4.99  Steam  target…",
+    "  " + cheap.url(),
+    "大神 絶景版       $14.99  Steam  target…",
+    "  " + close.url(),
+    "",
+    "",
+  }
+  if !reflect.DeepEqual(lines, expected) {
+    t.Errorf("Expected %q but got %q", expected, lines)
+  }
+  if strings.Contains(buffer.String(), "\x1b]8;;") {
+    t.Errorf("Unexpected hyperlink without -hyperlinks:\n%q", buffer.String())
+  }
+}
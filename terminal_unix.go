@@ -0,0 +1,22 @@
+//go:build linux || darwin
+// +build linux darwin
+
+package main
+
+import (
+  "os"
+  "syscall"
+  "unsafe"
+)
+
+// Returns the number of columns of the terminal, 0 if |file| isn't one.
+func terminalSize(file *os.File) int {
+  var size struct {
+    rows, columns, xPixels, yPixels uint16
+  }
+  _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, file.Fd(), uintptr(syscall.TIOCGWINSZ), uintptr(unsafe.Pointer(&size)))
+  if errno != 0 {
+    return 0
+  }
+  return int(size.columns)
+}
@@ -0,0 +1,850 @@
+package main
+
+import (
+  "bytes"
+  "encoding/csv"
+  "encoding/json"
+  "errors"
+  "fmt"
+  "io"
+  "os"
+  "strconv"
+  "strings"
+)
+
+// Backend names, see Game.backend.
+var allStores = []string{"steam", "fanatical", "humblebundle", "gmg", "loaded"}
+
+// Store identifiers pinned in the watchlist.
+// When set, the pinned store page is fetched directly instead of searching by name (see productpage.go).
+type pinnedIds struct {
+  // At most one of steamId and steamBundleId is set.
+  steamId int
+  steamBundleId int
+
+  fanaticalSlug string
+  hbPath string
+  gmgPath string
+  loadedURL string
+}
+
+type gameCriteria struct {
+  name string
+  target priceTarget
+  pinned pinnedIds
+
+  // Tried in order when |name| finds nothing.
+  alternateNames []string
+
+  // Set for entries that expand to several games.
+  query steamQuery
+  // Name of the watchlist entry that expanded to this game (e.g. "series: Foobar").
+  // Empty for regular entries.
+  group string
+
+  // Stores considered for the price, all of them if empty.
+  stores []string
+  // Edition searched before the base game (e.g. "GOTY Edition").
+  edition string
+
+  tags []string
+  // Tag the game is reported under (see reportTag), empty if untagged.
+  tag string
+  notes string
+  // Higher is more important.
+  priority int
+
+  // User the entry belongs to in a shared watchlist, empty if unset.
+  owner string
+  // Entries of the other owners for the same game.
+  // They are fetched once with this entry, see groupByGame.
+  sharedWith []gameCriteria
+}
+
+func newGameCriteria(name string, target priceTarget) gameCriteria {
+  criteria := gameCriteria{name: name, target: target}
+  // Steam references are resolved directly to their canonical name.
+  criteria.pinned, _ = parseSteamReference(name)
+  criteria.query, _ = parseSteamQuery(name)
+  return criteria
+}
+
+func (criteria gameCriteria) allowsStore(store string) bool {
+  if len(criteria.stores) == 0 {
+    return true
+  }
+  for _, allowedStore := range criteria.stores {
+    if allowedStore == store {
+      return true
+    }
+  }
+  return false
+}
+
+// Returns the names to search for |name|, in order:
+// the preferred edition, |name| itself and the alternate names.
+func (criteria gameCriteria) searchNames(name string) []string {
+  names := []string{}
+  if criteria.edition != "" && !strings.HasSuffix(normalizeName(name), normalizeName(criteria.edition)) {
+    names = append(names, name + " " + criteria.edition)
+  }
+  names = append(names, name)
+  return append(names, criteria.alternateNames...)
+}
+
+// Tags are case insensitive.
+func normalizeTag(tag string) string {
+  return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// Parses a ',' separated list of tags (see -tags).
+func parseTags(list string) []string {
+  tags := []string{}
+  for _, tag := range strings.Split(list, ",") {
+    tag = normalizeTag(tag)
+    if tag != "" {
+      tags = append(tags, tag)
+    }
+  }
+  return tags
+}
+
+// The tags are normalized by parseTags and toCriteria.
+func hasTag(tags []string, tag string) bool {
+  for _, candidate := range tags {
+    if candidate == tag {
+      return true
+    }
+  }
+  return false
+}
+
+// Whether the entry has one of |tags|, true if |tags| is empty.
+func (criteria gameCriteria) hasAnyTag(tags []string) bool {
+  if len(tags) == 0 {
+    return true
+  }
+  for _, tag := range criteria.tags {
+    if hasTag(tags, tag) {
+      return true
+    }
+  }
+  return false
+}
+
+// Returns the first tag of the entry among |tags| (any tag if |tags| is empty).
+func (criteria gameCriteria) reportTag(tags []string) string {
+  for _, tag := range criteria.tags {
+    if len(tags) == 0 || hasTag(tags, tag) {
+      return tag
+    }
+  }
+  return ""
+}
+
+// Maps a store name (or one of its aliases) to its backend name.
+func parseStore(store string) (string, error) {
+  store = strings.ToLower(strings.TrimSpace(store))
+  switch store {
+    case "hb":
+      return "humblebundle", nil
+    case "greenmangaming":
+      return "gmg", nil
+  }
+  for _, knownStore := range allStores {
+    if store == knownStore {
+      return store, nil
+    }
+  }
+  return "", fmt.Errorf("Unknown store \"%s\"", store)
+}
+
+// Splits a ';' separated list, dropping empty values.
+func splitList(list string) []string {
+  values := []string{}
+  for _, value := range strings.Split(list, ";") {
+    value = strings.TrimSpace(value)
+    if value != "" {
+      values = append(values, value)
+    }
+  }
+  return values
+}
+
+// Parses the watchlist without checking for duplicates.
+// "-" reads the watchlist from stdin, as JSON if it starts with '{' and as CSV otherwise.
+func parseWatchlistFile(fileName string) ([]gameCriteria, error) {
+  if fileName == "-" {
+    data, err := io.ReadAll(os.Stdin)
+    if err != nil {
+      return []gameCriteria{}, err
+    }
+    if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+      return parseJSONWatchlist(data)
+    }
+    return parseCSVWatchlist(bytes.NewReader(data))
+  }
+
+  // Check that the file exist and is valid.
+  // For some reason, os.Open doesn't return an error when opening a directory.
+  stats, err := os.Stat(fileName)
+  if err != nil {
+    return []gameCriteria{}, err
+  }
+  if stats.IsDir() {
+    return []gameCriteria{}, errors.New("File is a directory")
+  }
+
+  data, err := os.ReadFile(fileName)
+  if err != nil {
+    return []gameCriteria{}, err
+  }
+
+  if isJSONWatchlist(fileName) {
+    return parseJSONWatchlist(data)
+  }
+  return parseCSVWatchlist(bytes.NewReader(data))
+}
+
+// Rules for games present several times across the watchlists, see -duplicates.
+const (
+  // Keep the first entry and warn about the others.
+  cDuplicatesWarn = "warn"
+  // Keep the entry with the lowest target price.
+  cDuplicatesLowest = "lowest"
+)
+
+func parseDuplicatesRule(rule string) (string, error) {
+  switch rule {
+    case cDuplicatesWarn, cDuplicatesLowest:
+      return rule, nil
+  }
+  return "", fmt.Errorf("Unknown duplicates rule \"%s\" (expected %s or %s)", rule, cDuplicatesWarn, cDuplicatesLowest)
+}
+
+// Whether |a| and |b| designate the same game: same Steam ids when both are pinned,
+// same name (modulo normalization) otherwise. Editions share names ("DOOM" 2016 and 1993)
+// so pinned ids that differ are different games.
+func isSameGame(a gameCriteria, b gameCriteria) bool {
+  aPinned := a.pinned.steamId != 0 || a.pinned.steamBundleId != 0
+  bPinned := b.pinned.steamId != 0 || b.pinned.steamBundleId != 0
+  if aPinned && bPinned {
+    return a.pinned.steamId == b.pinned.steamId && a.pinned.steamBundleId == b.pinned.steamBundleId
+  }
+  return normalizeName(a.name) == normalizeName(b.name)
+}
+
+// Merges the duplicated games of each owner according to |rule|, keeping the order of the first occurrences.
+// Returns the merged games along with the warnings for the dropped entries.
+func mergeDuplicates(criteria []gameCriteria, rule string) ([]gameCriteria, []string) {
+  merged := []gameCriteria{}
+  warnings := []string{}
+  for _, criterium := range criteria {
+    duplicateIdx := -1
+    for idx, kept := range merged {
+      if kept.owner == criterium.owner && isSameGame(kept, criterium) {
+        duplicateIdx = idx
+        break
+      }
+    }
+    if duplicateIdx == -1 {
+      merged = append(merged, criterium)
+      continue
+    }
+
+    kept := merged[duplicateIdx]
+    if rule == cDuplicatesLowest {
+      keptPrice, keptHasPrice := kept.target.highestPrice()
+      price, hasPrice := criterium.target.highestPrice()
+      if keptHasPrice && hasPrice {
+        if price < keptPrice {
+          merged[duplicateIdx] = criterium
+        }
+        continue
+      }
+    }
+    warnings = append(warnings, fmt.Sprintf("Duplicated game \"%s\"%s, keeping the target %v over %v", kept.name, kept.ownerSuffix(), kept.target, criterium.target))
+  }
+  return merged, warnings
+}
+
+// Returns " for <owner>" for messages, "" if the entry has no owner.
+func (criteria gameCriteria) ownerSuffix() string {
+  if criteria.owner == "" {
+    return ""
+  }
+  return " for " + criteria.owner
+}
+
+// Groups the entries of the different owners for the same game:
+// the first entry is kept with the others in its sharedWith.
+func groupByGame(criteria []gameCriteria) []gameCriteria {
+  grouped := []gameCriteria{}
+  for _, criterium := range criteria {
+    sharedIdx := -1
+    for idx, kept := range grouped {
+      if isSameGame(kept, criterium) {
+        sharedIdx = idx
+        break
+      }
+    }
+    if sharedIdx == -1 {
+      grouped = append(grouped, criterium)
+      continue
+    }
+    grouped[sharedIdx].sharedWith = append(grouped[sharedIdx].sharedWith, criterium)
+  }
+  return grouped
+}
+
+// Returns the name of the watchlist entry: the series entry for the games of a series.
+func (criteria gameCriteria) entryName() string {
+  if criteria.group != "" {
+    return criteria.group
+  }
+  return criteria.name
+}
+
+// Returns the entry and the entries sharing its game.
+func (criteria gameCriteria) allOwners() []gameCriteria {
+  return append([]gameCriteria{criteria}, criteria.sharedWith...)
+}
+
+// Returns the criteria to fetch the game for all the owners:
+// the stores and target conditions are the union of theirs.
+func (criteria gameCriteria) fetchCriteria() gameCriteria {
+  fetch := criteria
+  fetch.sharedWith = nil
+  for _, shared := range criteria.sharedWith {
+    fetch.target = append(append(priceTarget{}, fetch.target...), shared.target...)
+    if len(fetch.stores) == 0 || len(shared.stores) == 0 {
+      fetch.stores = nil
+      continue
+    }
+    for _, store := range shared.stores {
+      if !fetch.allowsStore(store) {
+        fetch.stores = append(append([]string{}, fetch.stores...), store)
+      }
+    }
+  }
+  return fetch
+}
+
+// Reads and merges the watchlists, see mergeDuplicates.
+// The warnings are printed on stderr.
+func readGamesFromFiles(fileNames []string, duplicatesRule string) ([]gameCriteria, error) {
+  criteria := []gameCriteria{}
+  for _, fileName := range fileNames {
+    fileCriteria, err := parseWatchlistFile(fileName)
+    if err != nil {
+      return []gameCriteria{}, fmt.Errorf("%s: %v", fileName, err)
+    }
+    criteria = append(criteria, fileCriteria...)
+  }
+
+  criteria, warnings := mergeDuplicates(criteria, duplicatesRule)
+  for _, warning := range warnings {
+    fmt.Fprintln(os.Stderr, warning)
+  }
+  return criteria, nil
+}
+
+// The CSV format is one game per line: "name[, target][, key=value...]".
+// Blank lines and lines starting with '#' are ignored. Names containing
+// commas must be quoted: "Foo, Bar", 10
+//
+// The first row can also be a header naming the columns among "name",
+// "target" and the option keys (e.g. "name,target,stores,notes").
+// The rows are then mapped by column, with "key=value" options allowed
+// in extra columns.
+//
+// In shared watchlists, a "[owner=<user>]" line starts the section of a user:
+// the following entries belong to them unless they have an owner option.
+func parseCSVWatchlist(reader io.Reader) ([]gameCriteria, error) {
+  criteria := make([]gameCriteria, 0)
+  csvReader := newCSVWatchlistReader(reader)
+  var header csvHeader
+  sectionOwner := ""
+  for {
+    records, err := csvReader.Read()
+    // Handle EOF as a special error.
+    if err == io.EOF {
+      break
+    }
+    if err != nil {
+      return []gameCriteria{}, err
+    }
+    if isBlankRecord(records) {
+      continue
+    }
+    line, _ := csvReader.FieldPos(0)
+
+    if owner, isSection := parseOwnerSection(records); isSection {
+      sectionOwner = owner
+      continue
+    }
+    if len(criteria) == 0 && header == nil {
+      if parsedHeader, isHeader := parseCSVHeader(records); isHeader {
+        header = parsedHeader
+        continue
+      }
+    }
+    if header != nil {
+      records = header.toRecord(records)
+    }
+
+    entry, err := parseCSVRecord(records)
+    if err != nil {
+      return []gameCriteria{}, fmt.Errorf("line %d: %v", line, err)
+    }
+    if entry.Owner == "" {
+      entry.Owner = sectionOwner
+    }
+    criterium, err := entry.toCriteria(defaultTarget)
+    if err != nil {
+      return []gameCriteria{}, fmt.Errorf("line %d: %v", line, err)
+    }
+    criteria = append(criteria, criterium)
+  }
+  return criteria, nil
+}
+
+func newCSVWatchlistReader(reader io.Reader) *csv.Reader {
+  csvReader := csv.NewReader(reader)
+  csvReader.Comment = '#'
+  // The target and options are optional.
+  csvReader.FieldsPerRecord = -1
+  // Allows quoted names after a space: Foo, "Bar, The".
+  csvReader.TrimLeadingSpace = true
+  return csvReader
+}
+
+// Lines with only spaces are not skipped by csv.Reader.
+func isBlankRecord(record []string) bool {
+  for _, field := range record {
+    if strings.TrimSpace(field) != "" {
+      return false
+    }
+  }
+  return true
+}
+
+// Returns the owner of a "[owner=<user>]" section line, "" for "[owner=]" which ends the sections.
+// The "owner=" prefix is required so that bracketed game names ("[REDACTED]") aren't sections.
+func parseOwnerSection(record []string) (string, bool) {
+  if len(record) != 1 {
+    return "", false
+  }
+  line := strings.TrimSpace(record[0])
+  if !strings.HasPrefix(line, "[") || !strings.HasSuffix(line, "]") {
+    return "", false
+  }
+  keyValue := strings.SplitN(line[1:len(line) - 1], "=", 2)
+  if len(keyValue) != 2 || strings.ToLower(strings.TrimSpace(keyValue[0])) != "owner" {
+    return "", false
+  }
+  return strings.TrimSpace(keyValue[1]), true
+}
+
+// Column names of a CSV watchlist with a header row, see parseCSVWatchlist.
+type csvHeader []string
+
+// Columns allowed in a header: the name, the target and the option keys (see parseCSVRecord).
+var csvColumns = []string{"name", "target", "steam", "bundle", "fanatical", "hb", "gmg", "loaded", "alt", "stores", "edition", "tags", "notes", "priority", "owner"}
+
+// A header has a "name" column and only known columns.
+func parseCSVHeader(record []string) (csvHeader, bool) {
+  header := csvHeader{}
+  hasName := false
+  for _, field := range record {
+    column := strings.ToLower(strings.TrimSpace(field))
+    known := false
+    for _, knownColumn := range csvColumns {
+      if column == knownColumn {
+        known = true
+        break
+      }
+    }
+    if !known {
+      return nil, false
+    }
+    hasName = hasName || column == "name"
+    header = append(header, column)
+  }
+  if !hasName {
+    return nil, false
+  }
+  return header, true
+}
+
+// Converts a row mapped by |header| to a headerless record.
+// Empty cells are dropped and extra columns are kept as is.
+func (header csvHeader) toRecord(row []string) []string {
+  record := []string{""}
+  for idx, field := range row {
+    if idx >= len(header) {
+      record = append(record, field)
+      continue
+    }
+    field = strings.TrimSpace(field)
+    switch {
+      case header[idx] == "name":
+        record[0] = field
+      case field == "":
+        continue
+      case header[idx] == "target":
+        record = append(record, field)
+      default:
+        record = append(record, header[idx] + "=" + field)
+    }
+  }
+  return record
+}
+
+// Converts a headerless record to a row mapped by |header|.
+// Values without a column are added as extra "key=value" columns.
+func (header csvHeader) fromRecord(record []string) []string {
+  row := make([]string, len(header))
+  extras := []string{}
+  for idx, field := range record {
+    column, value := "name", field
+    if idx > 0 {
+      var isOption bool
+      column, value, isOption = strings.Cut(field, "=")
+      if !isOption {
+        column, value = "target", field
+      }
+    }
+
+    placed := false
+    for columnIdx, headerColumn := range header {
+      if headerColumn == column && row[columnIdx] == "" {
+        row[columnIdx] = value
+        placed = true
+        break
+      }
+    }
+    if !placed {
+      extras = append(extras, field)
+    }
+  }
+  return append(row, extras...)
+}
+
+// Parses a CSV record into an entry.
+// The columns after the name are either the target or "key=value" options.
+// List values (stores, tags) are separated by ';'.
+// The values are validated by watchlistEntry.toCriteria.
+func parseCSVRecord(record []string) (watchlistEntry, error) {
+  entry := watchlistEntry{Name: record[0]}
+  for _, column := range record[1:] {
+    if !strings.Contains(column, "=") {
+      // Targets are expressions, see parseTarget.
+      target, err := json.Marshal(strings.TrimSpace(column))
+      if err != nil {
+        return watchlistEntry{}, err
+      }
+      entry.Target = target
+      continue
+    }
+
+    key, value, _ := strings.Cut(column, "=")
+    key = strings.TrimSpace(key)
+    value = strings.TrimSpace(value)
+    if value == "" {
+      return watchlistEntry{}, fmt.Errorf("Missing value for option \"%s\"", key)
+    }
+
+    switch key {
+      case "steam", "bundle":
+        id, err := strconv.Atoi(value)
+        if err != nil {
+          return watchlistEntry{}, fmt.Errorf("Invalid Steam id \"%s\" (err = %+v)", value, err)
+        }
+        if key == "steam" {
+          entry.pinned().Steam = id
+        } else {
+          entry.pinned().Bundle = id
+        }
+      case "fanatical":
+        entry.pinned().Fanatical = value
+      case "hb":
+        entry.pinned().HB = value
+      case "gmg":
+        entry.pinned().GMG = value
+      case "loaded":
+        entry.pinned().Loaded = value
+      case "alt":
+        entry.AlternateNames = append(entry.AlternateNames, value)
+      case "stores":
+        entry.Stores = append(entry.Stores, splitList(value)...)
+      case "edition":
+        entry.Edition = value
+      case "tags":
+        entry.Tags = append(entry.Tags, splitList(value)...)
+      case "notes":
+        entry.Notes = value
+      case "priority":
+        priority, err := strconv.Atoi(value)
+        if err != nil {
+          return watchlistEntry{}, fmt.Errorf("Invalid priority \"%s\" (err = %+v)", value, err)
+        }
+        entry.Priority = priority
+      case "owner":
+        entry.Owner = value
+      default:
+        return watchlistEntry{}, fmt.Errorf("Unknown option \"%s\"", key)
+    }
+  }
+  return entry, nil
+}
+
+// JSON watchlist format:
+// {
+//   "defaultTarget": "75%",
+//   "games": [
+//     {
+//       "name": "Foobar",
+//       "target": 10,
+//       "stores": ["steam", "fanatical"],
+//       "edition": "GOTY Edition",
+//       "pinned": {"steam": 12345, "fanatical": "foobar"},
+//       "alternateNames": ["Foo Bar"],
+//       "tags": ["coop"],
+//       "notes": "Wait for the sale",
+//       "priority": 2,
+//       "owner": "alice"
+//     }
+//   ]
+// }
+// Only "name" is mandatory (or "pinned.steam"/"pinned.bundle").
+// Targets are either a price or a target expression (see parseTarget).
+type watchlistPinnedIds struct {
+  Steam int `json:"steam,omitempty"`
+  Bundle int `json:"bundle,omitempty"`
+  Fanatical string `json:"fanatical,omitempty"`
+  HB string `json:"hb,omitempty"`
+  GMG string `json:"gmg,omitempty"`
+  Loaded string `json:"loaded,omitempty"`
+}
+
+// The fields are omitted when empty so entries can be written back compactly.
+type watchlistEntry struct {
+  Name string `json:"name,omitempty"`
+  // Either a number or a string, nil if missing.
+  Target json.RawMessage `json:"target,omitempty"`
+  Stores []string `json:"stores,omitempty"`
+  Edition string `json:"edition,omitempty"`
+  Pinned *watchlistPinnedIds `json:"pinned,omitempty"`
+  AlternateNames []string `json:"alternateNames,omitempty"`
+  Tags []string `json:"tags,omitempty"`
+  Notes string `json:"notes,omitempty"`
+  Priority int `json:"priority,omitempty"`
+  Owner string `json:"owner,omitempty"`
+}
+
+// Returns the pinned ids, allocating them if needed.
+func (entry *watchlistEntry) pinned() *watchlistPinnedIds {
+  if entry.Pinned == nil {
+    entry.Pinned = &watchlistPinnedIds{}
+  }
+  return entry.Pinned
+}
+
+func parseJSONTarget(raw json.RawMessage) (priceTarget, error) {
+  var price float32
+  if json.Unmarshal(raw, &price) == nil {
+    if price < 0 {
+      return nil, fmt.Errorf("Invalid negative target %v", price)
+    }
+    return priceTarget{targetCondition{belowPrice, price}}, nil
+  }
+
+  var expr string
+  err := json.Unmarshal(raw, &expr)
+  if err != nil {
+    return nil, fmt.Errorf("Invalid target %s, expected a number or a string", string(raw))
+  }
+  return parseTarget(expr)
+}
+
+func (entry watchlistEntry) toCriteria(target priceTarget) (gameCriteria, error) {
+  pinned := watchlistPinnedIds{}
+  if entry.Pinned != nil {
+    pinned = *entry.Pinned
+  }
+
+  name := strings.TrimSpace(entry.Name)
+  if name == "" {
+    // The name is resolved from Steam for pinned entries.
+    if pinned.Steam != 0 {
+      name = fmt.Sprintf("app/%d", pinned.Steam)
+    } else if pinned.Bundle != 0 {
+      name = fmt.Sprintf("bundle/%d", pinned.Bundle)
+    } else {
+      return gameCriteria{}, errors.New("Missing \"name\"")
+    }
+  }
+
+  criteria := newGameCriteria(name, target)
+  if entry.Target != nil {
+    entryTarget, err := parseJSONTarget(entry.Target)
+    if err != nil {
+      return gameCriteria{}, err
+    }
+    criteria.target = entryTarget
+  }
+
+  if pinned.Steam != 0 && pinned.Bundle != 0 {
+    return gameCriteria{}, errors.New("Only one of the steam and bundle ids can be pinned")
+  }
+  if pinned.Steam != 0 {
+    criteria.pinned.steamId = pinned.Steam
+  }
+  if pinned.Bundle != 0 {
+    criteria.pinned.steamBundleId = pinned.Bundle
+  }
+  criteria.pinned.fanaticalSlug = pinned.Fanatical
+  criteria.pinned.hbPath = pinned.HB
+  criteria.pinned.gmgPath = pinned.GMG
+  criteria.pinned.loadedURL = pinned.Loaded
+
+  for _, store := range entry.Stores {
+    parsedStore, err := parseStore(store)
+    if err != nil {
+      return gameCriteria{}, err
+    }
+    criteria.stores = append(criteria.stores, parsedStore)
+  }
+
+  criteria.alternateNames = entry.AlternateNames
+  criteria.edition = strings.TrimSpace(entry.Edition)
+  for _, tag := range entry.Tags {
+    if tag = normalizeTag(tag); tag != "" {
+      criteria.tags = append(criteria.tags, tag)
+    }
+  }
+  criteria.notes = entry.Notes
+  criteria.priority = entry.Priority
+  criteria.owner = strings.TrimSpace(entry.Owner)
+  return criteria, nil
+}
+
+// Returns the 1-based line containing |offset|.
+func lineOf(data []byte, offset int64) int {
+  if offset > int64(len(data)) {
+    offset = int64(len(data))
+  }
+  return bytes.Count(data[:offset], []byte("\n")) + 1
+}
+
+// Returns the line of the next token after |offset|.
+// json.Decoder.InputOffset points after the previous token so we skip the separators.
+func lineOfNextToken(data []byte, offset int64) int {
+  for offset < int64(len(data)) && strings.IndexByte(" \t\r\n,:", data[offset]) != -1 {
+    offset++
+  }
+  return lineOf(data, offset)
+}
+
+// Annotates a decoding error with the most precise line we know.
+func jsonLineError(data []byte, line int, err error) error {
+  var syntaxErr *json.SyntaxError
+  var typeErr *json.UnmarshalTypeError
+  if errors.As(err, &syntaxErr) {
+    line = lineOf(data, syntaxErr.Offset)
+  } else if errors.As(err, &typeErr) {
+    line = lineOf(data, typeErr.Offset)
+  }
+  return fmt.Errorf("line %d: %v", line, err)
+}
+
+// Consumes the next token, checking that it is |delim|.
+func expectJSONDelim(decoder *json.Decoder, data []byte, delim json.Delim) error {
+  line := lineOfNextToken(data, decoder.InputOffset())
+  token, err := decoder.Token()
+  if err != nil {
+    return jsonLineError(data, line, err)
+  }
+  if token != delim {
+    return fmt.Errorf("line %d: Expected '%v' but got %v", line, delim, token)
+  }
+  return nil
+}
+
+func parseJSONWatchlist(data []byte) ([]gameCriteria, error) {
+  // We walk the document token by token (instead of a single Unmarshal)
+  // so we know where each entry starts for error reporting.
+  decoder := json.NewDecoder(bytes.NewReader(data))
+  decoder.DisallowUnknownFields()
+
+  err := expectJSONDelim(decoder, data, '{')
+  if err != nil {
+    return []gameCriteria{}, err
+  }
+
+  // The entries are converted once the whole document is read
+  // as "defaultTarget" can come after "games".
+  type lineEntry struct {
+    line int
+    entry watchlistEntry
+  }
+  entries := []lineEntry{}
+  fileTarget := defaultTarget
+  for decoder.More() {
+    line := lineOfNextToken(data, decoder.InputOffset())
+    token, err := decoder.Token()
+    if err != nil {
+      return []gameCriteria{}, jsonLineError(data, line, err)
+    }
+
+    switch token {
+      case "defaultTarget":
+        line := lineOfNextToken(data, decoder.InputOffset())
+        var raw json.RawMessage
+        err := decoder.Decode(&raw)
+        if err != nil {
+          return []gameCriteria{}, jsonLineError(data, line, err)
+        }
+        fileTarget, err = parseJSONTarget(raw)
+        if err != nil {
+          return []gameCriteria{}, fmt.Errorf("line %d: %v", line, err)
+        }
+      case "games":
+        err = expectJSONDelim(decoder, data, '[')
+        if err != nil {
+          return []gameCriteria{}, err
+        }
+        for decoder.More() {
+          line := lineOfNextToken(data, decoder.InputOffset())
+          var entry watchlistEntry
+          err := decoder.Decode(&entry)
+          if err != nil {
+            return []gameCriteria{}, jsonLineError(data, line, err)
+          }
+          entries = append(entries, lineEntry{line, entry})
+        }
+        err = expectJSONDelim(decoder, data, ']')
+        if err != nil {
+          return []gameCriteria{}, err
+        }
+      default:
+        return []gameCriteria{}, fmt.Errorf("line %d: Unknown field %v", line, token)
+    }
+  }
+
+  err = expectJSONDelim(decoder, data, '}')
+  if err != nil {
+    return []gameCriteria{}, err
+  }
+
+  criteria := make([]gameCriteria, 0)
+  for _, lineEntry := range entries {
+    criterium, err := lineEntry.entry.toCriteria(fileTarget)
+    if err != nil {
+      return []gameCriteria{}, fmt.Errorf("line %d: %v", lineEntry.line, err)
+    }
+    criteria = append(criteria, criterium)
+  }
+  return criteria, nil
+}
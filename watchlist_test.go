@@ -0,0 +1,315 @@
+package main
+
+import (
+  "reflect"
+  "strings"
+  "testing"
+)
+
+func TestParseCSVWatchlist(t *testing.T) {
+  input := `Foobar
+Barfoo, 10
+app/12345, 5, fanatical=foobar-slug
+Foo Bar, stores=steam;hb, edition=GOTY Edition, tags=coop;rpg, notes=Wait, priority=3
+`
+  criteria, err := parseCSVWatchlist(strings.NewReader(input))
+  if err != nil {
+    t.Fatalf("Unexpected error %+v", err)
+  }
+
+  expected := []gameCriteria{
+    gameCriteria{name: "Foobar", target: defaultTarget},
+    gameCriteria{name: "Barfoo", target: priceTarget{targetCondition{belowPrice, 10}}},
+    gameCriteria{name: "app/12345", target: priceTarget{targetCondition{belowPrice, 5}}, pinned: pinnedIds{steamId: 12345, fanaticalSlug: "foobar-slug"}},
+    gameCriteria{name: "Foo Bar", target: defaultTarget, stores: []string{"steam", "humblebundle"}, edition: "GOTY Edition", tags: []string{"coop", "rpg"}, notes: "Wait", priority: 3},
+  }
+  if !reflect.DeepEqual(criteria, expected) {
+    t.Errorf("Expected %+v but got %+v", expected, criteria)
+  }
+}
+
+func TestParseCSVWatchlistWithHeader(t *testing.T) {
+  input := `# Shared watchlist
+Name, Target, Stores, Notes
+
+# Strategy
+"Foo, The", 10, steam;gmg, Wait for the sale
+  	
+Barfoo, , , , priority=2
+`
+  criteria, err := parseCSVWatchlist(strings.NewReader(input))
+  if err != nil {
+    t.Fatalf("Unexpected error %+v", err)
+  }
+
+  expected := []gameCriteria{
+    gameCriteria{name: "Foo, The", target: priceTarget{targetCondition{belowPrice, 10}}, stores: []string{"steam", "gmg"}, notes: "Wait for the sale"},
+    gameCriteria{name: "Barfoo", target: defaultTarget, priority: 2},
+  }
+  if !reflect.DeepEqual(criteria, expected) {
+    t.Errorf("Expected %+v but got %+v", expected, criteria)
+  }
+}
+
+func TestParseCSVWatchlistErrors(t *testing.T) {
+  tt := []struct {
+    name string
+    input string
+    expectedError string
+  } {
+    {"Invalid price", "Foobar\nBarfoo, ten\n", "line 2: Invalid target \"ten\""},
+    {"Unknown option", "Foobar, foo=bar\n", "line 1: Unknown option \"foo\""},
+    {"Unknown store", "Foobar\n\"Bar, foo\", stores=foo\n", "line 2: Unknown store \"foo\""},
+    {"Header", "# Comment\nname,stores\n\nFoobar,foo\n", "line 4: Unknown store \"foo\""},
+  }
+
+  for _, tc := range(tt) {
+    t.Run(tc.name, func(t *testing.T) {
+      _, err := parseCSVWatchlist(strings.NewReader(tc.input))
+      if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+        t.Errorf("Expected error \"%s\" but got %+v", tc.expectedError, err)
+      }
+    })
+  }
+}
+
+func TestParseJSONWatchlist(t *testing.T) {
+  input := `{
+  "games": [
+    {"name": "Foobar"},
+    {"name": "Barfoo", "target": 0},
+    {"pinned": {"steam": 12345, "hb": "/foobar"}, "target": 5},
+    {
+      "name": "Foo Bar",
+      "stores": ["steam", "greenmangaming"],
+      "edition": "GOTY Edition",
+      "alternateNames": ["FooBar"],
+      "tags": ["coop"],
+      "notes": "Wait",
+      "priority": 3
+    }
+  ]
+}`
+  criteria, err := parseJSONWatchlist([]byte(input))
+  if err != nil {
+    t.Fatalf("Unexpected error %+v", err)
+  }
+
+  expected := []gameCriteria{
+    gameCriteria{name: "Foobar", target: defaultTarget},
+    gameCriteria{name: "Barfoo", target: priceTarget{targetCondition{belowPrice, 0}}},
+    gameCriteria{name: "app/12345", target: priceTarget{targetCondition{belowPrice, 5}}, pinned: pinnedIds{steamId: 12345, hbPath: "/foobar"}},
+    gameCriteria{name: "Foo Bar", target: defaultTarget, stores: []string{"steam", "gmg"}, edition: "GOTY Edition", alternateNames: []string{"FooBar"}, tags: []string{"coop"}, notes: "Wait", priority: 3},
+  }
+  if !reflect.DeepEqual(criteria, expected) {
+    t.Errorf("Expected %+v but got %+v", expected, criteria)
+  }
+}
+
+func TestParseJSONWatchlistErrors(t *testing.T) {
+  tt := []struct {
+    name string
+    input string
+    expectedError string
+  } {
+    {"Missing name", "{\"games\": [\n  {\"name\": \"Foobar\"},\n  {\"target\": 10}\n]}", "line 3: Missing \"name\""},
+    {"Unknown field", "{\"games\": [\n  {\"name\": \"Foobar\",\n   \"price\": 10}\n]}", "line 2: json: unknown field \"price\""},
+    {"Invalid type", "{\"games\": [\n  {\"name\": \"Foobar\",\n   \"priority\": \"10\"}\n]}", "line 3: json: cannot unmarshal"},
+    {"Invalid target", "{\"games\": [\n  {\"name\": \"Foobar\",\n   \"target\": \"ten\"}\n]}", "line 2: Invalid target \"ten\""},
+    {"Unknown store", "{\"games\": [\n  {\"name\": \"Foobar\", \"stores\": [\"foo\"]}\n]}", "line 2: Unknown store \"foo\""},
+    {"Unknown top-level field", "{\n  \"game\": []\n}", "line 2: Unknown field game"},
+    {"Syntax error", "{\"games\": [\n  {\"name\": \"Foobar\"}\n  {\"name\": \"Barfoo\"}\n]}", "line 3:"},
+  }
+
+  for _, tc := range(tt) {
+    t.Run(tc.name, func(t *testing.T) {
+      _, err := parseJSONWatchlist([]byte(tc.input))
+      if err == nil || !strings.Contains(err.Error(), tc.expectedError) {
+        t.Errorf("Expected error \"%s\" but got %+v", tc.expectedError, err)
+      }
+    })
+  }
+}
+
+func TestParseJSONWatchlistTargets(t *testing.T) {
+  input := `{
+  "games": [
+    {"name": "Foobar"},
+    {"name": "Barfoo", "target": "10|low"}
+  ],
+  "defaultTarget": "75%"
+}`
+  criteria, err := parseJSONWatchlist([]byte(input))
+  if err != nil {
+    t.Fatalf("Unexpected error %+v", err)
+  }
+
+  expected := []gameCriteria{
+    gameCriteria{name: "Foobar", target: priceTarget{targetCondition{percentOffBase, 75}}},
+    gameCriteria{name: "Barfoo", target: priceTarget{targetCondition{belowPrice, 10}, targetCondition{atHistoricalLow, 0}}},
+  }
+  if !reflect.DeepEqual(criteria, expected) {
+    t.Errorf("Expected %+v but got %+v", expected, criteria)
+  }
+}
+
+func TestMergeDuplicates(t *testing.T) {
+  target := func(price float32) priceTarget {
+    return priceTarget{targetCondition{belowPrice, price}}
+  }
+  low := priceTarget{targetCondition{atHistoricalLow, 0}}
+  criteria := []gameCriteria{
+    newGameCriteria("Foobar", target(10)),
+    newGameCriteria("app/12", target(5)),
+    newGameCriteria("foobar", target(7)),
+    newGameCriteria("Barfoo", low),
+    newGameCriteria("https://store.steampowered.com/app/12/", target(3)),
+    newGameCriteria("BARFOO", target(2)),
+  }
+
+  tt := []struct {
+    rule string
+    expected []gameCriteria
+    expectedWarnings int
+  } {
+    {cDuplicatesWarn, []gameCriteria{criteria[0], criteria[1], criteria[3]}, 3},
+    {cDuplicatesLowest, []gameCriteria{criteria[2], criteria[4], criteria[3]}, 1},
+  }
+
+  for _, tc := range(tt) {
+    t.Run(tc.rule, func(t *testing.T) {
+      merged, warnings := mergeDuplicates(criteria, tc.rule)
+      if !reflect.DeepEqual(merged, tc.expected) {
+        t.Errorf("Expected %+v but got %+v", tc.expected, merged)
+      }
+      if len(warnings) != tc.expectedWarnings {
+        t.Errorf("Expected %d warnings but got %+v", tc.expectedWarnings, warnings)
+      }
+    })
+  }
+}
+
+func TestIsSameGame(t *testing.T) {
+  pinned := func(name string, steamId int) gameCriteria {
+    criteria := newGameCriteria(name, defaultTarget)
+    criteria.pinned = pinnedIds{steamId: steamId}
+    return criteria
+  }
+  tt := []struct {
+    name string
+    a gameCriteria
+    b gameCriteria
+    expected bool
+  } {
+    {"Same name", newGameCriteria("DOOM", defaultTarget), newGameCriteria("doom", defaultTarget), true},
+    {"Same ids", pinned("DOOM", 379720), pinned("DOOM (2016)", 379720), true},
+    {"Different ids with the same name", pinned("DOOM", 2280), pinned("DOOM", 379720), false},
+    {"Only one pinned", pinned("DOOM", 2280), newGameCriteria("DOOM", defaultTarget), true},
+  }
+
+  for _, tc := range(tt) {
+    t.Run(tc.name, func(t *testing.T) {
+      if got := isSameGame(tc.a, tc.b); got != tc.expected {
+        t.Errorf("Expected %v but got %v", tc.expected, got)
+      }
+    })
+  }
+
+  merged, warnings := mergeDuplicates([]gameCriteria{pinned("DOOM", 2280), pinned("DOOM", 379720)}, cDuplicatesWarn)
+  if len(merged) != 2 || len(warnings) != 0 {
+    t.Errorf("Expected both DOOM entries to be kept but got %+v (warnings %+v)", merged, warnings)
+  }
+  if grouped := groupByGame([]gameCriteria{pinned("DOOM", 2280), pinned("DOOM", 379720)}); len(grouped) != 2 {
+    t.Errorf("Expected two fetches but got %+v", grouped)
+  }
+}
+
+func TestTags(t *testing.T) {
+  criteria, err := watchlistEntry{Name: "Foobar", Tags: []string{"rpg", " Coop"}}.toCriteria(defaultTarget)
+  if err != nil {
+    t.Fatalf("Unexpected error %+v", err)
+  }
+  tt := []struct {
+    filter string
+    expectedMatch bool
+    expectedTag string
+  } {
+    {"", true, "rpg"},
+    {"COOP", true, "coop"},
+    {"solo, RPG", true, "rpg"},
+    {"solo", false, ""},
+  }
+
+  for _, tc := range(tt) {
+    tags := parseTags(tc.filter)
+    if criteria.hasAnyTag(tags) != tc.expectedMatch {
+      t.Errorf("Expected hasAnyTag to be %v for %s", tc.expectedMatch, tc.filter)
+    }
+    if tag := criteria.reportTag(tags); tag != tc.expectedTag {
+      t.Errorf("Expected tag %s for %s but got %s", tc.expectedTag, tc.filter, tag)
+    }
+  }
+
+  if !(gameCriteria{}).hasAnyTag(nil) || (gameCriteria{}).hasAnyTag([]string{"coop"}) {
+    t.Errorf("Untagged games should only match without filter")
+  }
+}
+
+func TestParseCSVWatchlistOwners(t *testing.T) {
+  input := `Foobar, 10
+[owner=alice]
+Foobar, 5
+Barfoo, owner=bob
+[ owner = bob ]
+Foobar, low
+[REDACTED]
+[owner=]
+[Owner]
+`
+  criteria, err := parseCSVWatchlist(strings.NewReader(input))
+  if err != nil {
+    t.Fatalf("Unexpected error %+v", err)
+  }
+
+  owners := []string{}
+  names := []string{}
+  for _, criterium := range criteria {
+    owners = append(owners, criterium.owner)
+    names = append(names, criterium.name)
+  }
+  if !reflect.DeepEqual(owners, []string{"", "alice", "bob", "bob", "bob", ""}) {
+    t.Errorf("Unexpected owners %+v", owners)
+  }
+  if !reflect.DeepEqual(names[4:], []string{"[REDACTED]", "[Owner]"}) {
+    t.Errorf("Expected the bracketed names to be games but got %+v", names)
+  }
+
+  merged, warnings := mergeDuplicates(criteria, cDuplicatesWarn)
+  if len(merged) != 6 || len(warnings) != 0 {
+    t.Errorf("Expected the games of different owners to be kept but got %+v (warnings %+v)", merged, warnings)
+  }
+}
+
+func TestGroupByGame(t *testing.T) {
+  alice := gameCriteria{name: "Foobar", target: priceTarget{targetCondition{belowPrice, 5}}, stores: []string{"steam"}, owner: "alice"}
+  bob := gameCriteria{name: "foobar", target: priceTarget{targetCondition{percentOffBase, 75}}, stores: []string{"gmg"}, owner: "bob"}
+  other := gameCriteria{name: "Barfoo", owner: "bob"}
+
+  grouped := groupByGame([]gameCriteria{alice, other, bob})
+  if len(grouped) != 2 || len(grouped[0].allOwners()) != 2 || len(grouped[1].allOwners()) != 1 {
+    t.Fatalf("Unexpected grouping %+v", grouped)
+  }
+
+  fetch := grouped[0].fetchCriteria()
+  if !reflect.DeepEqual(fetch.stores, []string{"steam", "gmg"}) || !fetch.target.needsBasePrice() {
+    t.Errorf("Expected the union of the stores and targets but got %+v", fetch)
+  }
+  if !reflect.DeepEqual(grouped[0].allOwners()[0].stores, []string{"steam"}) {
+    t.Errorf("The owner's stores should not change but got %+v", grouped[0])
+  }
+
+  bob.stores = nil
+  if fetch = groupByGame([]gameCriteria{alice, bob})[0].fetchCriteria(); len(fetch.stores) != 0 {
+    t.Errorf("Expected all the stores to be fetched but got %+v", fetch.stores)
+  }
+}
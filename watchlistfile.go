@@ -0,0 +1,492 @@
+package main
+
+import (
+  "bytes"
+  "encoding/csv"
+  "encoding/json"
+  "errors"
+  "fmt"
+  "io"
+  "io/fs"
+  "os"
+  "path/filepath"
+  "reflect"
+  "strings"
+)
+
+// Writing back to watchlist files.
+// Existing content is kept as is: CSV entries are appended (or edited in place)
+// and JSON entries are added at the end of "games".
+
+func isJSONWatchlist(fileName string) bool {
+  return strings.EqualFold(filepath.Ext(fileName), ".json")
+}
+
+// Returns the CSV record for |entry|, see parseCSVWatchlist.
+func (entry watchlistEntry) csvRecord() []string {
+  pinned := watchlistPinnedIds{}
+  if entry.Pinned != nil {
+    pinned = *entry.Pinned
+  }
+
+  name := entry.Name
+  if name == "" {
+    if pinned.Steam != 0 {
+      name = fmt.Sprintf("app/%d", pinned.Steam)
+      pinned.Steam = 0
+    } else if pinned.Bundle != 0 {
+      name = fmt.Sprintf("bundle/%d", pinned.Bundle)
+      pinned.Bundle = 0
+    }
+  }
+  record := []string{name}
+
+  if entry.Target != nil {
+    var expr string
+    if json.Unmarshal(entry.Target, &expr) != nil {
+      // Numbers are written as is.
+      expr = string(entry.Target)
+    }
+    record = append(record, expr)
+  }
+
+  if pinned.Steam != 0 {
+    record = append(record, fmt.Sprintf("steam=%d", pinned.Steam))
+  }
+  if pinned.Bundle != 0 {
+    record = append(record, fmt.Sprintf("bundle=%d", pinned.Bundle))
+  }
+  options := []struct {
+    key string
+    value string
+  } {
+    {"fanatical", pinned.Fanatical},
+    {"hb", pinned.HB},
+    {"gmg", pinned.GMG},
+    {"loaded", pinned.Loaded},
+    {"stores", strings.Join(entry.Stores, ";")},
+    {"edition", entry.Edition},
+    {"tags", strings.Join(entry.Tags, ";")},
+    {"notes", entry.Notes},
+    {"owner", entry.Owner},
+  }
+  for _, option := range options {
+    if option.value != "" {
+      record = append(record, option.key + "=" + option.value)
+    }
+  }
+  for _, alternateName := range entry.AlternateNames {
+    record = append(record, "alt=" + alternateName)
+  }
+  if entry.Priority != 0 {
+    record = append(record, fmt.Sprintf("priority=%d", entry.Priority))
+  }
+  return record
+}
+
+// Returns the header of the CSV watchlist, nil if it has none.
+func findCSVHeader(data []byte) csvHeader {
+  csvReader := newCSVWatchlistReader(bytes.NewReader(data))
+  for {
+    record, err := csvReader.Read()
+    if err != nil {
+      return nil
+    }
+    if !isBlankRecord(record) {
+      header, _ := parseCSVHeader(record)
+      return header
+    }
+  }
+}
+
+// Returns the owner of the last "[owner=<user>]" section, "" if there is none.
+func findLastOwnerSection(data []byte) string {
+  owner := ""
+  csvReader := newCSVWatchlistReader(bytes.NewReader(data))
+  for {
+    record, err := csvReader.Read()
+    if err != nil {
+      return owner
+    }
+    if sectionOwner, isSection := parseOwnerSection(record); isSection {
+      owner = sectionOwner
+    }
+  }
+}
+
+// Returns the CSV row for |entry|, mapped by |header| if there is one.
+func (entry watchlistEntry) csvRow(header csvHeader) []string {
+  if header == nil {
+    return entry.csvRecord()
+  }
+  return header.fromRecord(entry.csvRecord())
+}
+
+func appendToCSVWatchlist(data []byte, entries []watchlistEntry) ([]byte, error) {
+  header := findCSVHeader(data)
+  var buf bytes.Buffer
+  buf.Write(data)
+  if len(data) > 0 && data[len(data) - 1] != '\n' {
+    buf.WriteByte('\n')
+  }
+
+  writer := csv.NewWriter(&buf)
+  // Entries without owner must not end up in the last section.
+  if findLastOwnerSection(data) != "" {
+    err := writer.Write([]string{"[owner=]"})
+    if err != nil {
+      return nil, err
+    }
+  }
+  for _, entry := range entries {
+    err := writer.Write(entry.csvRow(header))
+    if err != nil {
+      return nil, err
+    }
+  }
+  writer.Flush()
+  return buf.Bytes(), writer.Error()
+}
+
+// The "games" array of a JSON watchlist, located in the document so entries can be
+// added or rewritten without reordering or reformatting the rest of the file.
+type jsonGames struct {
+  data []byte
+  elements []json.RawMessage
+  // Offsets of the elements in |data|.
+  starts []int
+  ends []int
+  // Offsets right after '[' and of ']', -1 if there is no "games".
+  open int
+  close int
+  // Offset of the closing '}' of the document.
+  end int
+}
+
+func parseJSONGames(data []byte) (*jsonGames, error) {
+  games := &jsonGames{data: data, open: -1, close: -1}
+  decoder := json.NewDecoder(bytes.NewReader(data))
+  token, err := decoder.Token()
+  if err != nil {
+    return nil, err
+  }
+  if token != json.Delim('{') {
+    return nil, errors.New("The JSON watchlist must be an object")
+  }
+  for decoder.More() {
+    key, err := decoder.Token()
+    if err != nil {
+      return nil, err
+    }
+    if key != "games" {
+      var skipped json.RawMessage
+      err = decoder.Decode(&skipped)
+      if err != nil {
+        return nil, err
+      }
+      continue
+    }
+
+    token, err = decoder.Token()
+    if err != nil {
+      return nil, err
+    }
+    if token != json.Delim('[') {
+      return nil, errors.New("\"games\" must be an array")
+    }
+    games.open = int(decoder.InputOffset())
+    for decoder.More() {
+      var element json.RawMessage
+      err = decoder.Decode(&element)
+      if err != nil {
+        return nil, err
+      }
+      end := int(decoder.InputOffset())
+      games.elements = append(games.elements, element)
+      games.starts = append(games.starts, end - len(element))
+      games.ends = append(games.ends, end)
+    }
+    _, err = decoder.Token()
+    if err != nil {
+      return nil, err
+    }
+    games.close = int(decoder.InputOffset()) - 1
+  }
+  _, err = decoder.Token()
+  if err != nil {
+    return nil, err
+  }
+  games.end = int(decoder.InputOffset()) - 1
+  return games, nil
+}
+
+// Returns the indentation of the line of |offset|, "" if something precedes it on the line.
+func lineIndent(data []byte, offset int) string {
+  lineStart := bytes.LastIndexByte(data[:offset], '\n') + 1
+  indent := data[lineStart:offset]
+  if len(bytes.TrimSpace(indent)) > 0 {
+    return ""
+  }
+  return string(indent)
+}
+
+// Returns the indentation of the entries, "" for a single-line document.
+func (g *jsonGames) indent() string {
+  if len(g.elements) > 0 {
+    return lineIndent(g.data, g.starts[0])
+  }
+  if !bytes.Contains(g.data, []byte("\n")) {
+    return ""
+  }
+  if g.open >= 0 {
+    return lineIndent(g.data, bytes.LastIndex(g.data[:g.open], []byte("\"games\""))) + "  "
+  }
+  return "    "
+}
+
+func (g *jsonGames) marshal(entry watchlistEntry) (json.RawMessage, error) {
+  indent := g.indent()
+  if indent == "" {
+    return json.Marshal(entry)
+  }
+  return json.MarshalIndent(entry, indent, "  ")
+}
+
+// Returns the document with |elements| as "games", the rest of it being unchanged.
+// The separators and indentation of the existing elements are reused.
+func (g *jsonGames) replace(elements []json.RawMessage) []byte {
+  indent := g.indent()
+  separator := ", "
+  leading := ""
+  trailing := ""
+  if indent != "" {
+    separator = ",\n" + indent
+    leading = "\n" + indent
+    trailing = "\n" + indent[:len(indent) - 2]
+  }
+  if len(g.elements) > 0 {
+    leading = string(g.data[g.open:g.starts[0]])
+    trailing = string(g.data[g.ends[len(g.ends) - 1]:g.close])
+  }
+  if len(g.elements) > 1 {
+    separator = string(g.data[g.ends[0]:g.starts[1]])
+  }
+
+  array := []byte(leading)
+  for i, element := range elements {
+    if i > 0 {
+      array = append(array, separator...)
+    }
+    array = append(array, element...)
+  }
+  if len(elements) > 0 {
+    array = append(array, trailing...)
+  }
+
+  output := []byte{}
+  if g.open >= 0 {
+    output = append(output, g.data[:g.open]...)
+    output = append(output, array...)
+    return append(output, g.data[g.close:]...)
+  }
+
+  // No "games" yet: it is added as the last key.
+  lastValue := len(bytes.TrimRight(g.data[:g.end], " \t\r\n"))
+  output = append(output, g.data[:lastValue]...)
+  if g.data[lastValue - 1] != '{' {
+    output = append(output, ',')
+  }
+  if indent != "" {
+    output = append(output, "\n" + indent[:len(indent) - 2]...)
+  } else if g.data[lastValue - 1] != '{' {
+    output = append(output, ' ')
+  }
+  output = append(output, "\"games\": ["...)
+  output = append(output, array...)
+  output = append(output, ']')
+  return append(output, g.data[lastValue:]...)
+}
+
+func appendToJSONWatchlist(data []byte, entries []watchlistEntry) ([]byte, error) {
+  if len(bytes.TrimSpace(data)) == 0 {
+    data = []byte("{\n}\n")
+  }
+  games, err := parseJSONGames(data)
+  if err != nil {
+    return nil, err
+  }
+
+  elements := append([]json.RawMessage{}, games.elements...)
+  for _, entry := range entries {
+    element, err := games.marshal(entry)
+    if err != nil {
+      return nil, err
+    }
+    elements = append(elements, element)
+  }
+  return games.replace(elements), nil
+}
+
+// Appends |entries| to the watchlist, creating the file if needed.
+func appendToWatchlist(fileName string, entries []watchlistEntry) error {
+  data, err := os.ReadFile(fileName)
+  if err != nil && !errors.Is(err, fs.ErrNotExist) {
+    return err
+  }
+
+  var output []byte
+  if isJSONWatchlist(fileName) {
+    output, err = appendToJSONWatchlist(data, entries)
+  } else {
+    output, err = appendToCSVWatchlist(data, entries)
+  }
+  if err != nil {
+    return err
+  }
+  return os.WriteFile(fileName, output, 0644)
+}
+
+// Called on each watchlist entry by editWatchlist.
+// Returns the updated entry and whether to keep it.
+// |entry| must not be modified in place (e.g. through entry.Pinned).
+type entryEditor func(entry watchlistEntry) (watchlistEntry, bool)
+
+func isChanged(before watchlistEntry, after watchlistEntry) bool {
+  return !reflect.DeepEqual(before, after)
+}
+
+// Returns the offset of the start of |line| (1-based) in |data|.
+func lineOffset(data []byte, line int) int {
+  offset := 0
+  for ; line > 1; line-- {
+    offset += bytes.IndexByte(data[offset:], '\n') + 1
+  }
+  return offset
+}
+
+func editCSVWatchlist(data []byte, edit entryEditor) ([]byte, error) {
+  var buf bytes.Buffer
+  var header csvHeader
+  seenRecord := false
+  sectionOwner := ""
+  // Records are located with their offsets so that unchanged records and what's between
+  // them (blank lines, comments and the header) are written back verbatim, including
+  // quoted fields spanning several lines.
+  csvReader := newCSVWatchlistReader(bytes.NewReader(data))
+  written := 0
+  for {
+    record, err := csvReader.Read()
+    if err == io.EOF {
+      break
+    }
+    if err != nil {
+      return nil, err
+    }
+    line, _ := csvReader.FieldPos(0)
+    start := lineOffset(data, line)
+    end := int(csvReader.InputOffset())
+    if isBlankRecord(record) || strings.HasPrefix(record[0], "#") {
+      continue
+    }
+
+    if owner, isSection := parseOwnerSection(record); isSection {
+      sectionOwner = owner
+      continue
+    }
+    if !seenRecord {
+      seenRecord = true
+      if parsedHeader, isHeader := parseCSVHeader(record); isHeader {
+        header = parsedHeader
+        continue
+      }
+    }
+    if header != nil {
+      record = header.toRecord(record)
+    }
+    entry, err := parseCSVRecord(record)
+    if err != nil {
+      return nil, fmt.Errorf("line %d: %v", line, err)
+    }
+    sectionEntry := entry.Owner == ""
+    if sectionEntry {
+      entry.Owner = sectionOwner
+    }
+
+    editedEntry, keep := edit(entry)
+    if keep && !isChanged(entry, editedEntry) {
+      continue
+    }
+    buf.Write(data[written:start])
+    written = end
+    if !keep {
+      continue
+    }
+    // The owner is still given by the section.
+    if sectionEntry && editedEntry.Owner == sectionOwner {
+      editedEntry.Owner = ""
+    }
+    writer := csv.NewWriter(&buf)
+    err = writer.Write(editedEntry.csvRow(header))
+    if err != nil {
+      return nil, err
+    }
+    writer.Flush()
+    if writer.Error() != nil {
+      return nil, writer.Error()
+    }
+    // The csv.Writer always ends the record with a newline.
+    if !bytes.HasSuffix(data[start:end], []byte("\n")) {
+      buf.Truncate(buf.Len() - 1)
+    }
+  }
+  buf.Write(data[written:])
+  return buf.Bytes(), nil
+}
+
+func editJSONWatchlist(data []byte, edit entryEditor) ([]byte, error) {
+  games, err := parseJSONGames(data)
+  if err != nil {
+    return nil, err
+  }
+
+  editedElements := []json.RawMessage{}
+  for _, element := range games.elements {
+    var entry watchlistEntry
+    err = json.Unmarshal(element, &entry)
+    if err != nil {
+      return nil, err
+    }
+
+    editedEntry, keep := edit(entry)
+    if !keep {
+      continue
+    }
+    if isChanged(entry, editedEntry) {
+      element, err = games.marshal(editedEntry)
+      if err != nil {
+        return nil, err
+      }
+    }
+    editedElements = append(editedElements, element)
+  }
+  return games.replace(editedElements), nil
+}
+
+// Rewrites the watchlist by calling |edit| on each entry.
+func editWatchlist(fileName string, edit entryEditor) error {
+  data, err := os.ReadFile(fileName)
+  if err != nil {
+    return err
+  }
+
+  var output []byte
+  if isJSONWatchlist(fileName) {
+    output, err = editJSONWatchlist(data, edit)
+  } else {
+    output, err = editCSVWatchlist(data, edit)
+  }
+  if err != nil {
+    return err
+  }
+  return os.WriteFile(fileName, output, 0644)
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+  "encoding/json"
+  "testing"
+)
+
+// Drops "Bar" and sets a target of 5 on "Foo".
+func testEditor(entry watchlistEntry) (watchlistEntry, bool) {
+  if entry.matches("bar") {
+    return entry, false
+  }
+  if entry.matches("foo") {
+    entry.Target = rawTarget("5")
+  }
+  return entry, true
+}
+
+func TestEditCSVWatchlist(t *testing.T) {
+  input := "# My games\nFoo, 10, steam=12\n\nBar\n\"Baz, The\", low\n"
+  output, err := editCSVWatchlist([]byte(input), testEditor)
+  if err != nil {
+    t.Fatalf("Unexpected error %+v", err)
+  }
+  expected := "# My games\nFoo,5,steam=12\n\n\"Baz, The\", low\n"
+  if string(output) != expected {
+    t.Errorf("Expected %q but got %q", expected, string(output))
+  }
+}
+
+func TestEditCSVWatchlistWithMultilineFields(t *testing.T) {
+  input := "name,notes,target\nBar,\"Line\nFoo\",10\nFoo,\"First\nSecond\",10\nBaz,,10"
+  output, err := editCSVWatchlist([]byte(input), testEditor)
+  if err != nil {
+    t.Fatalf("Unexpected error %+v", err)
+  }
+  expected := "name,notes,target\nFoo,\"First\nSecond\",5\nBaz,,10"
+  if string(output) != expected {
+    t.Errorf("Expected %q but got %q", expected, string(output))
+  }
+}
+
+func TestEditCSVWatchlistWithHeader(t *testing.T) {
+  input := "name,notes,target\nFoo,Keep,10\nBar\n"
+  output, err := editCSVWatchlist([]byte(input), testEditor)
+  if err != nil {
+    t.Fatalf("Unexpected error %+v", err)
+  }
+  expected := "name,notes,target\nFoo,Keep,5\n"
+  if string(output) != expected {
+    t.Errorf("Expected %q but got %q", expected, string(output))
+  }
+
+  output, err = appendToCSVWatchlist(output, []watchlistEntry{watchlistEntry{Name: "Baz", Pinned: &watchlistPinnedIds{Steam: 12}}})
+  if err != nil {
+    t.Fatalf("Unexpected error %+v", err)
+  }
+  expected += "Baz,,,steam=12\n"
+  if string(output) != expected {
+    t.Errorf("Expected %q but got %q", expected, string(output))
+  }
+}
+
+func TestEditCSVWatchlistWithSections(t *testing.T) {
+  input := "Foo, 10\n[owner=alice]\nFoo, 10\nBar\n"
+  setAliceTarget := func(entry watchlistEntry) (watchlistEntry, bool) {
+    if entry.Owner == "alice" && entry.matches("foo") {
+      entry.Target = rawTarget("5")
+    }
+    return entry, true
+  }
+  output, err := editCSVWatchlist([]byte(input), setAliceTarget)
+  if err != nil {
+    t.Fatalf("Unexpected error %+v", err)
+  }
+  expected := "Foo, 10\n[owner=alice]\nFoo,5\nBar\n"
+  if string(output) != expected {
+    t.Errorf("Expected %q but got %q", expected, string(output))
+  }
+
+  output, err = appendToCSVWatchlist(output, []watchlistEntry{watchlistEntry{Name: "Baz"}, watchlistEntry{Name: "Baz", Owner: "bob"}})
+  if err != nil {
+    t.Fatalf("Unexpected error %+v", err)
+  }
+  expected += "[owner=]\nBaz\nBaz,owner=bob\n"
+  if string(output) != expected {
+    t.Errorf("Expected %q but got %q", expected, string(output))
+  }
+}
+
+func TestEditJSONWatchlist(t *testing.T) {
+  input := `{"defaultTarget": 3, "games": [{"name": "Foo", "target": "75%"}, {"name": "Bar"}, {"name": "app/12", "notes": "Keep"}]}`
+  output, err := editJSONWatchlist([]byte(input), testEditor)
+  if err != nil {
+    t.Fatalf("Unexpected error %+v", err)
+  }
+
+  var document struct {
+    DefaultTarget json.RawMessage
+    Games []watchlistEntry
+  }
+  err = json.Unmarshal(output, &document)
+  if err != nil {
+    t.Fatalf("Invalid output %s (err = %+v)", output, err)
+  }
+  if string(document.DefaultTarget) != "3" {
+    t.Errorf("Expected the default target to be kept but got %s", output)
+  }
+  if len(document.Games) != 2 || string(document.Games[0].Target) != "5" || document.Games[1].Notes != "Keep" {
+    t.Errorf("Unexpected games %s", output)
+  }
+}
+
+func TestJSONWatchlistKeepsFormatting(t *testing.T) {
+  input := "{\n  \"games\": [\n    {\"name\": \"Foo\"},\n    {\"name\": \"Bar\"}\n  ],\n  \"defaultTarget\": 3\n}\n"
+  tt := []struct {
+    name string
+    edit func(data []byte) ([]byte, error)
+    expected string
+  } {
+    {"Append", func(data []byte) ([]byte, error) {
+      return appendToJSONWatchlist(data, []watchlistEntry{{Name: "Baz"}})
+    }, "{\n  \"games\": [\n    {\"name\": \"Foo\"},\n    {\"name\": \"Bar\"},\n    {\n      \"name\": \"Baz\"\n    }\n  ],\n  \"defaultTarget\": 3\n}\n"},
+    {"Edit", func(data []byte) ([]byte, error) {
+      return editJSONWatchlist(data, testEditor)
+    }, "{\n  \"games\": [\n    {\n      \"name\": \"Foo\",\n      \"target\": 5\n    }\n  ],\n  \"defaultTarget\": 3\n}\n"},
+    {"Append without games", func(data []byte) ([]byte, error) {
+      return appendToJSONWatchlist([]byte("{\"defaultTarget\": 3}"), []watchlistEntry{{Name: "Baz"}})
+    }, "{\"defaultTarget\": 3, \"games\": [{\"name\":\"Baz\"}]}"},
+  }
+
+  for _, tc := range(tt) {
+    t.Run(tc.name, func(t *testing.T) {
+      output, err := tc.edit([]byte(input))
+      if err != nil {
+        t.Fatalf("Unexpected error %+v", err)
+      }
+      if string(output) != tc.expected {
+        t.Errorf("Expected %q but got %q", tc.expected, output)
+      }
+    })
+  }
+}
+
+func TestEntryMatches(t *testing.T) {
+  entry := watchlistEntry{Name: "Foo: The Game", Pinned: &watchlistPinnedIds{Steam: 12}}
+  for _, name := range []string{"foo: the game", "app/12", "https://store.steampowered.com/app/12/Foo/"} {
+    if !entry.matches(name) {
+      t.Errorf("Expected %+v to match %s", entry, name)
+    }
+  }
+  for _, name := range []string{"foo", "app/13", "bundle/12"} {
+    if entry.matches(name) {
+      t.Errorf("Expected %+v not to match %s", entry, name)
+    }
+  }
+}